@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 附件大小上限和允许的类型，由 InitStorage 按配置设置
+var (
+	attachmentMaxSize      int64 = 20 << 20
+	attachmentAllowedTypes       = strings.Split(defaultAttachmentTypes, ",")
+)
+
+// 默认允许的附件类型：图片、音视频、文本、PDF、Office 文档和压缩包
+const defaultAttachmentTypes = "image/*,video/*,audio/*,text/*,application/pdf,application/json," +
+	"application/msword,application/vnd.ms-excel,application/vnd.ms-powerpoint,application/vnd.openxmlformats-officedocument.*," +
+	"application/zip,application/x-7z-compressed,application/x-rar-compressed,application/gzip,application/x-gzip"
+
+// 附件按上传时间排序
+func orderAttachments(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at").Order("id")
+}
+
+// 附件相关接口
+func uploadTaskAttachment(c *gin.Context) {
+	id, err := parseIDParam(c, "taskId")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var task Task
+	if err := DB.Preload("Stage").First(&task, id).Error; err != nil {
+		respondError(c, dbError(err, "TASK_NOT_FOUND"))
+		return
+	}
+
+	uploadAttachment(c, Attachment{ProjectID: task.Stage.ProjectID, TaskID: &task.ID})
+}
+
+func uploadStageAttachment(c *gin.Context) {
+	id, err := parseIDParam(c, "stageId")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var stage Stage
+	if err := DB.First(&stage, id).Error; err != nil {
+		respondError(c, dbError(err, "STAGE_NOT_FOUND"))
+		return
+	}
+
+	uploadAttachment(c, Attachment{ProjectID: stage.ProjectID, StageID: &stage.ID})
+}
+
+// 接收 multipart 表单中的 file 字段，检查大小和类型后写入存储
+func uploadAttachment(c *gin.Context, attachment Attachment) {
+	// 留出表单其他部分的余量，超出时 FormFile 返回错误
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, attachmentMaxSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			respondError(c, newAPIError(http.StatusRequestEntityTooLarge, "ATTACHMENT_TOO_LARGE", attachmentMaxSize>>20))
+			return
+		}
+		respondError(c, badRequest("ATTACHMENT_FILE_REQUIRED"))
+		return
+	}
+	if header.Size > attachmentMaxSize {
+		respondError(c, newAPIError(http.StatusRequestEntityTooLarge, "ATTACHMENT_TOO_LARGE", attachmentMaxSize>>20))
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		respondError(c, internalError("ATTACHMENT_UPLOAD_FAILED", err))
+		return
+	}
+	defer file.Close()
+
+	// 读取文件头判断实际类型
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		respondError(c, internalError("ATTACHMENT_UPLOAD_FAILED", err))
+		return
+	}
+	head = head[:n]
+
+	fileName := path.Base(strings.ReplaceAll(header.Filename, "\\", "/"))
+	contentType, ok := attachmentContentType(fileName, head)
+	if !ok {
+		respondError(c, newAPIError(http.StatusUnsupportedMediaType, "ATTACHMENT_TYPE_NOT_ALLOWED", contentType))
+		return
+	}
+
+	key, err := attachmentKey(attachment.ProjectID, fileName)
+	if err != nil {
+		respondError(c, internalError("ATTACHMENT_UPLOAD_FAILED", err))
+		return
+	}
+
+	body := io.MultiReader(bytes.NewReader(head), file)
+	if err := Storage.Put(c.Request.Context(), key, body, header.Size, contentType); err != nil {
+		log.Printf("写入附件失败: %v", err)
+		respondError(c, internalError("ATTACHMENT_UPLOAD_FAILED", err))
+		return
+	}
+
+	attachment.FileName = fileName
+	attachment.ContentType = contentType
+	attachment.Size = header.Size
+	attachment.StorageKey = key
+	if uploadedBy, err := strconv.ParseUint(c.PostForm("uploaded_by"), 10, 32); err == nil {
+		attachment.UploadedBy = uint(uploadedBy)
+	}
+
+	if err := DB.Create(&attachment).Error; err != nil {
+		// 记录保存失败时清理已写入的文件
+		if err := Storage.Delete(c.Request.Context(), key); err != nil {
+			log.Printf("清理附件文件 %s 失败: %v", key, err)
+		}
+		respondError(c, err)
+		return
+	}
+
+	publishChange("created", &attachment)
+	c.JSON(http.StatusCreated, attachment)
+}
+
+func downloadAttachment(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var attachment Attachment
+	if err := DB.First(&attachment, id).Error; err != nil {
+		respondError(c, dbError(err, "ATTACHMENT_NOT_FOUND"))
+		return
+	}
+
+	reader, err := Storage.Get(c.Request.Context(), attachment.StorageKey)
+	if errors.Is(err, errObjectNotFound) {
+		respondError(c, notFound("ATTACHMENT_FILE_MISSING"))
+		return
+	}
+	if err != nil {
+		log.Printf("读取附件 %d 失败: %v", attachment.ID, err)
+		respondError(c, internalError("ATTACHMENT_DOWNLOAD_FAILED", err))
+		return
+	}
+	defer reader.Close()
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName})
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, reader, map[string]string{
+		"Content-Disposition":    disposition,
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+func deleteAttachment(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var attachment Attachment
+	if err := DB.First(&attachment, id).Error; err != nil {
+		respondError(c, dbError(err, "ATTACHMENT_NOT_FOUND"))
+		return
+	}
+
+	if err := DB.Delete(&attachment).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	removeAttachmentFiles([]string{attachment.StorageKey})
+
+	publishChange("deleted", &attachment)
+	respondMessage(c, http.StatusOK, "ATTACHMENT_DELETED")
+}
+
+// 删除存储中的文件，记录已删除，失败只记录日志
+func removeAttachmentFiles(keys []string) {
+	for _, key := range keys {
+		if err := Storage.Delete(context.Background(), key); err != nil {
+			log.Printf("删除附件文件 %s 失败: %v", key, err)
+		}
+	}
+}
+
+// 确定附件类型：优先按扩展名，无法识别时按内容判断
+// 扩展名对应的类型和按内容识别出的具体类型都必须在允许列表内
+func attachmentContentType(fileName string, head []byte) (string, bool) {
+	sniffed := mediaType(http.DetectContentType(head))
+	contentType := mediaType(mime.TypeByExtension(strings.ToLower(path.Ext(fileName))))
+	if contentType == "" {
+		contentType = sniffed
+	}
+	if !allowedAttachmentType(contentType) {
+		return contentType, false
+	}
+	if sniffed != "application/octet-stream" && sniffed != "text/plain" && !allowedAttachmentType(sniffed) {
+		return sniffed, false
+	}
+	return contentType, true
+}
+
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mediaType
+}
+
+// 允许列表支持 image/* 这样的通配
+func allowedAttachmentType(contentType string) bool {
+	for _, pattern := range attachmentAllowedTypes {
+		if pattern == contentType || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(contentType, strings.TrimSuffix(pattern, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// 存储路径使用随机名称，保留原扩展名
+func attachmentKey(projectID uint, fileName string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("projects/%d/%s%s", projectID, hex.EncodeToString(buf), strings.ToLower(path.Ext(fileName))), nil
+}
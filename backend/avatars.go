@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// 头像限制：上传文件大小、原图尺寸，保存的头像和导出时嵌入的缩略图边长（像素）
+// 原图尺寸在完整解码前按图片头检查，4096×4096 解码后约占 64MB 内存
+const (
+	avatarMaxUpload    = 5 << 20
+	avatarMaxDimension = 4096
+	avatarSize         = 256
+	avatarThumbSize    = 48
+)
+
+// 上传成员头像，图片居中裁剪为正方形并缩放后以PNG保存
+func uploadAvatar(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var member TeamMember
+	if err := DB.First(&member, id).Error; err != nil {
+		respondError(c, dbError(err, "MEMBER_NOT_FOUND"))
+		return
+	}
+	if err := checkIfMatch(c, member.Version, member); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, avatarMaxUpload+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			respondError(c, newAPIError(http.StatusRequestEntityTooLarge, "AVATAR_TOO_LARGE", avatarMaxUpload>>20))
+			return
+		}
+		respondError(c, badRequest("ATTACHMENT_FILE_REQUIRED"))
+		return
+	}
+	if header.Size > avatarMaxUpload {
+		respondError(c, newAPIError(http.StatusRequestEntityTooLarge, "AVATAR_TOO_LARGE", avatarMaxUpload>>20))
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		respondError(c, internalError("AVATAR_UPLOAD_FAILED", err))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		respondError(c, internalError("AVATAR_UPLOAD_FAILED", err))
+		return
+	}
+
+	avatar, err := resizeAvatar(data, avatarSize)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		respondError(c, internalError("AVATAR_UPLOAD_FAILED", err))
+		return
+	}
+	token := hex.EncodeToString(buf)
+	key := fmt.Sprintf("avatars/%d-%s.png", member.ID, token)
+	if err := Storage.Put(c.Request.Context(), key, bytes.NewReader(avatar), int64(len(avatar)), "image/png"); err != nil {
+		log.Printf("写入头像失败: %v", err)
+		respondError(c, internalError("AVATAR_UPLOAD_FAILED", err))
+		return
+	}
+
+	before := member
+	member.AvatarKey = key
+	member.Avatar = fmt.Sprintf("/api/v1/members/%d/avatar?v=%s", member.ID, token)
+	if err := saveVersioned(DB, &member); err != nil {
+		removeAttachmentFiles([]string{key})
+		respondError(c, err)
+		return
+	}
+	if before.AvatarKey != "" {
+		removeAttachmentFiles([]string{before.AvatarKey})
+	}
+
+	publishUpdate(&before, &member)
+	setETag(c, member.Version)
+	c.JSON(http.StatusOK, member)
+}
+
+// 返回成员上传的头像
+func getAvatar(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var member TeamMember
+	if err := DB.First(&member, id).Error; err != nil {
+		respondError(c, dbError(err, "MEMBER_NOT_FOUND"))
+		return
+	}
+	if member.AvatarKey == "" {
+		respondError(c, notFound("AVATAR_NOT_FOUND"))
+		return
+	}
+
+	reader, err := Storage.Get(c.Request.Context(), member.AvatarKey)
+	if errors.Is(err, errObjectNotFound) {
+		respondError(c, notFound("AVATAR_NOT_FOUND"))
+		return
+	}
+	if err != nil {
+		log.Printf("读取成员 %d 的头像失败: %v", member.ID, err)
+		respondError(c, internalError("AVATAR_DOWNLOAD_FAILED", err))
+		return
+	}
+	defer reader.Close()
+
+	// 每次上传的头像地址带有不同的 v 参数，内容不会变化，可以长期缓存
+	c.DataFromReader(http.StatusOK, -1, "image/png", reader, map[string]string{
+		"Cache-Control": "public, max-age=31536000, immutable",
+	})
+}
+
+// 删除成员上传的头像
+func deleteAvatar(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var member TeamMember
+	if err := DB.First(&member, id).Error; err != nil {
+		respondError(c, dbError(err, "MEMBER_NOT_FOUND"))
+		return
+	}
+	if err := checkIfMatch(c, member.Version, member); err != nil {
+		respondError(c, err)
+		return
+	}
+	if member.AvatarKey == "" {
+		respondError(c, notFound("AVATAR_NOT_FOUND"))
+		return
+	}
+
+	before := member
+	member.AvatarKey = ""
+	member.Avatar = ""
+	if err := saveVersioned(DB, &member); err != nil {
+		respondError(c, err)
+		return
+	}
+	removeAttachmentFiles([]string{before.AvatarKey})
+
+	publishUpdate(&before, &member)
+	setETag(c, member.Version)
+	c.JSON(http.StatusOK, member)
+}
+
+// 解码图片，居中裁剪为正方形后缩放到 size 像素，返回PNG数据
+func resizeAvatar(data []byte, size int) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width == 0 || config.Height == 0 {
+		return nil, newAPIError(http.StatusUnprocessableEntity, "AVATAR_INVALID_IMAGE")
+	}
+	if config.Width > avatarMaxDimension || config.Height > avatarMaxDimension {
+		return nil, newAPIError(http.StatusUnprocessableEntity, "AVATAR_DIMENSIONS_TOO_LARGE", avatarMaxDimension, avatarMaxDimension)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, newAPIError(http.StatusUnprocessableEntity, "AVATAR_INVALID_IMAGE")
+	}
+	return encodeSquare(src, size)
+}
+
+func encodeSquare(src image.Image, size int) ([]byte, error) {
+	bounds := src.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2))
+
+	// 小图不放大
+	if side < size {
+		size = side
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// 读取成员头像并生成导出用的缩略图
+func avatarThumbnail(ctx context.Context, key string) ([]byte, error) {
+	reader, err := Storage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	src, _, err := image.Decode(reader)
+	if err != nil {
+		return nil, err
+	}
+	return encodeSquare(src, avatarThumbSize)
+}
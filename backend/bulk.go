@@ -0,0 +1,308 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 单次批量请求最多包含的操作数
+const maxBulkOperations = 500
+
+// 批量任务操作
+// op=create 使用 data 创建任务；op=update 按合并补丁规则更新 id 对应的任务；op=delete 删除任务；
+// op=shift 将 id 对应的任务整体平移 days 个工作日（负数表示提前），给出 stage_id 时阶段连同其下所有任务一起平移
+type bulkTaskOperation struct {
+	Op      string                 `json:"op"`
+	ID      uint                   `json:"id"`
+	StageID uint                   `json:"stage_id"`
+	Version *uint                  `json:"version"` // 必填，作用同 If-Match；按阶段平移时为阶段的版本号
+	Days    int                    `json:"days"`
+	Data    map[string]interface{} `json:"data"`
+}
+
+// 每个操作的执行结果
+type bulkTaskResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	Status string `json:"status"` // ok, failed, rolled_back, skipped
+	Tasks  []Task `json:"tasks,omitempty"`
+	Stage  *Stage `json:"stage,omitempty"` // 按阶段平移时平移后的阶段
+	Error  gin.H  `json:"error,omitempty"`
+
+	previous       []Task   // 更新前的任务，用于发布事件
+	previousStage  *Stage   // 平移前的阶段
+	attachmentKeys []string // 删除任务后需要删除的附件文件
+}
+
+// 批量执行任务操作，全部成功才提交，任一失败则整体回滚
+func bulkTasks(c *gin.Context) {
+	var req struct {
+		Operations []bulkTaskOperation `json:"operations"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBulkOperations {
+		respondError(c, badRequest("INVALID_BULK_SIZE", maxBulkOperations))
+		return
+	}
+
+	results := make([]bulkTaskResult, len(req.Operations))
+	for i, op := range req.Operations {
+		results[i] = bulkTaskResult{Index: i, Op: op.Op, Status: "skipped"}
+	}
+
+	failedIndex := -1
+	var failure *APIError
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for i, op := range req.Operations {
+			if err := applyBulkTaskOperation(tx, op, &results[i]); err != nil {
+				failedIndex, failure = i, dbError(err, "TASK_NOT_FOUND")
+				return failure
+			}
+			results[i].Status = "ok"
+		}
+		return nil
+	})
+
+	if failure == nil && err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if failure != nil {
+		locale := getLocale(c)
+		for i := range results[:failedIndex] {
+			results[i].Status = "rolled_back"
+			results[i].Tasks = nil
+			results[i].Stage = nil
+		}
+		results[failedIndex].Status = "failed"
+		results[failedIndex].Tasks = nil
+		results[failedIndex].Stage = nil
+		results[failedIndex].Error = errorBody(locale, failure)
+
+		apiErr := &APIError{Status: failure.Status, Code: "BULK_OPERATION_FAILED", Args: []interface{}{failedIndex}, Cause: failure.Cause}
+		respondError(c, apiErr.WithDetails(gin.H{"failed_index": failedIndex, "results": results}))
+		return
+	}
+
+	for i, op := range req.Operations {
+		removeAttachmentFiles(results[i].attachmentKeys)
+		if results[i].Stage != nil {
+			publishUpdate(results[i].previousStage, results[i].Stage)
+		}
+		for j := range results[i].Tasks {
+			switch op.Op {
+			case "create":
+				publishChange("created", &results[i].Tasks[j])
+			case "delete":
+				publishChange("deleted", &results[i].Tasks[j])
+			default:
+				publishUpdate(&results[i].previous[j], &results[i].Tasks[j])
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// 在事务中执行一个操作，校验也在同一事务中读取，能看到本批次之前操作写入的数据
+func applyBulkTaskOperation(tx *gorm.DB, op bulkTaskOperation, result *bulkTaskResult) error {
+	switch op.Op {
+	case "create":
+		task := Task{Status: "pending", Priority: "medium", Version: 1}
+		fields := taskPatchFields(&task)
+		fields["stage_id"] = func(fe *fieldErrors, f string, v interface{}) { task.StageID = fe.asID(f, v) }
+		if err := applyMergePatch(op.Data, fields, false); err != nil {
+			return err
+		}
+		if err := validateTask(tx, &task); err != nil {
+			return err
+		}
+		order, err := nextOrder(tx, &Task{}, "stage_id", task.StageID)
+		if err != nil {
+			return err
+		}
+		task.Order = order
+		if err := tx.Create(&task).Error; err != nil {
+			return err
+		}
+		result.Tasks = []Task{task}
+		return nil
+
+	case "update":
+		task, err := loadBulkTask(tx, op)
+		if err != nil {
+			return err
+		}
+		before := task
+		if err := applyMergePatch(op.Data, taskPatchFields(&task), true); err != nil {
+			return err
+		}
+		if err := validateTask(tx, &task); err != nil {
+			return err
+		}
+		if err := saveVersioned(tx, &task); err != nil {
+			return err
+		}
+		result.Tasks, result.previous = []Task{task}, []Task{before}
+		return nil
+
+	case "delete":
+		task, err := loadBulkTask(tx, op)
+		if err != nil {
+			return err
+		}
+		keys, err := deleteTaskData(tx, task.ID)
+		if err != nil {
+			return err
+		}
+		res := tx.Where("version = ?", task.Version).Delete(&Task{}, task.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return versionConflict(task)
+		}
+		result.Tasks, result.attachmentKeys = []Task{task}, keys
+		return nil
+
+	case "shift":
+		if op.ID != 0 {
+			task, err := loadBulkTask(tx, op)
+			if err != nil {
+				return err
+			}
+			result.previous = []Task{task}
+			task.StartDate = addWorkDays(task.StartDate, op.Days)
+			task.EndDate = addWorkDays(task.EndDate, op.Days)
+			if err := validateTask(tx, &task); err != nil {
+				return err
+			}
+			if err := saveVersioned(tx, &task); err != nil {
+				return err
+			}
+			result.Tasks = []Task{task}
+			return nil
+		}
+		if op.StageID != 0 {
+			return shiftStage(tx, op, result)
+		}
+		return badRequest("BULK_TARGET_REQUIRED")
+	}
+
+	return badRequest("INVALID_BULK_OP", op.Op)
+}
+
+// 阶段连同其下所有任务整体平移：先写入平移后的任务，再平移并校验阶段，
+// 最后按新的阶段日期校验任务，任何一步失败都会随事务回滚
+func shiftStage(tx *gorm.DB, op bulkTaskOperation, result *bulkTaskResult) error {
+	var stage Stage
+	if err := tx.First(&stage, op.StageID).Error; err != nil {
+		return dbError(err, "STAGE_NOT_FOUND")
+	}
+	if op.Version == nil {
+		return newAPIError(http.StatusPreconditionRequired, "VERSION_REQUIRED")
+	}
+	if *op.Version != stage.Version {
+		return versionConflict(stage)
+	}
+	var tasks []Task
+	if err := tx.Where("stage_id = ?", op.StageID).Scopes(orderTasks).Find(&tasks).Error; err != nil {
+		return err
+	}
+
+	result.previous = append([]Task(nil), tasks...)
+	for i := range tasks {
+		tasks[i].StartDate = addWorkDays(tasks[i].StartDate, op.Days)
+		tasks[i].EndDate = addWorkDays(tasks[i].EndDate, op.Days)
+		if err := saveVersioned(tx, &tasks[i]); err != nil {
+			return err
+		}
+	}
+
+	before := stage
+	stage.StartDate = addWorkDays(stage.StartDate, op.Days)
+	stage.EndDate = addWorkDays(stage.EndDate, op.Days)
+	if err := validateStage(tx, &stage); err != nil {
+		return err
+	}
+	if err := saveVersioned(tx, &stage); err != nil {
+		return err
+	}
+
+	for i := range tasks {
+		if err := validateTask(tx, &tasks[i]); err != nil {
+			return err
+		}
+	}
+	result.Tasks, result.Stage, result.previousStage = tasks, &stage, &before
+	return nil
+}
+
+// 删除任务的评论、提及、通知、工时和附件记录，返回需要在提交后删除的附件文件
+// 进度快照保留为燃尽图的历史数据，删除事件会写入一条已删除的快照
+func deleteTaskData(tx *gorm.DB, taskID uint) ([]string, error) {
+	if err := tx.Exec("DELETE FROM comment_mentions WHERE comment_id IN (SELECT id FROM comments WHERE task_id = ?)", taskID).Error; err != nil {
+		return nil, err
+	}
+	for _, model := range []interface{}{&Comment{}, &Notification{}, &TimeEntry{}} {
+		if err := tx.Where("task_id = ?", taskID).Delete(model).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var keys []string
+	if err := tx.Model(&Attachment{}).Where("task_id = ?", taskID).Pluck("storage_key", &keys).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("task_id = ?", taskID).Delete(&Attachment{}).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// 读取操作对应的任务并检查版本号
+func loadBulkTask(tx *gorm.DB, op bulkTaskOperation) (Task, error) {
+	var task Task
+	if op.ID == 0 {
+		return task, badRequest("BULK_TARGET_REQUIRED")
+	}
+	if err := tx.First(&task, op.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return task, notFound("TASK_NOT_FOUND")
+		}
+		return task, err
+	}
+	if op.Version == nil {
+		return task, newAPIError(http.StatusPreconditionRequired, "VERSION_REQUIRED")
+	}
+	if *op.Version != task.Version {
+		return task, versionConflict(task)
+	}
+	return task, nil
+}
+
+// 按工作日平移日期（跳过周六周日），days 为负数时向前平移
+func addWorkDays(date time.Time, days int) time.Time {
+	if date.IsZero() {
+		return date
+	}
+	step := 1
+	if days < 0 {
+		step, days = -1, -days
+	}
+	for days > 0 {
+		date = date.AddDate(0, 0, step)
+		if weekday := date.Weekday(); weekday != time.Saturday && weekday != time.Sunday {
+			days--
+		}
+	}
+	return date
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddWorkDays(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	tests := []struct {
+		name string
+		date string
+		days int
+		want string
+	}{
+		{"零天", "2024-03-06", 0, "2024-03-06"},
+		{"周中向后", "2024-03-06", 2, "2024-03-08"},
+		{"跨周末向后", "2024-03-08", 1, "2024-03-11"},
+		{"跨周末向前", "2024-03-11", -1, "2024-03-08"},
+		{"从周六开始", "2024-03-09", 1, "2024-03-11"},
+		{"从周日向前", "2024-03-10", -1, "2024-03-08"},
+		{"两周", "2024-03-04", 10, "2024-03-18"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addWorkDays(day(tt.date), tt.days); !got.Equal(day(tt.want)) {
+				t.Errorf("addWorkDays(%s, %d) = %s, want %s", tt.date, tt.days, got.Format("2006-01-02"), tt.want)
+			}
+		})
+	}
+
+	if got := addWorkDays(time.Time{}, 3); !got.IsZero() {
+		t.Errorf("addWorkDays(zero, 3) = %s, want zero", got)
+	}
+}
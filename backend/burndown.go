@@ -0,0 +1,282 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// 同一任务同一天只保留一条快照，后写入的覆盖之前的
+var snapshotUpsert = clause.OnConflict{
+	Columns:   []clause.Column{{Name: "task_id"}, {Name: "date"}},
+	DoUpdates: clause.AssignmentColumns([]string{"project_id", "stage_id", "status", "progress", "work", "deleted"}),
+}
+
+// 任务工作量，以工期的工作日数计
+func taskWork(task Task) float64 {
+	return float64(calculateWorkDays(task.StartDate, task.EndDate))
+}
+
+func newTaskSnapshot(projectID uint, task Task, date time.Time) TaskSnapshot {
+	return TaskSnapshot{
+		ProjectID: projectID,
+		StageID:   task.StageID,
+		TaskID:    task.ID,
+		Date:      date,
+		Status:    task.Status,
+		Progress:  task.Progress,
+		Work:      taskWork(task),
+	}
+}
+
+// 每天为未结束项目的所有任务记录进度快照
+func snapshotTaskProgress(now time.Time) {
+	today := dateOnly(now)
+
+	var tasks []Task
+	if err := DB.Preload("Stage").
+		Where("stage_id IN (SELECT stages.id FROM stages JOIN projects ON projects.id = stages.project_id WHERE projects.status <> ?)", "completed").
+		Find(&tasks).Error; err != nil {
+		log.Printf("查询待快照任务失败: %v", err)
+		return
+	}
+	if len(tasks) == 0 {
+		return
+	}
+
+	snapshots := make([]TaskSnapshot, 0, len(tasks))
+	for _, task := range tasks {
+		snapshots = append(snapshots, newTaskSnapshot(task.Stage.ProjectID, task, today))
+	}
+	if err := DB.Clauses(snapshotUpsert).CreateInBatches(snapshots, 500).Error; err != nil {
+		log.Printf("记录任务进度快照失败: %v", err)
+		return
+	}
+	log.Printf("已记录 %d 个任务的进度快照", len(snapshots))
+}
+
+// 任务变更时更新当天的快照，使快照反映当天最后的进度
+// 任务删除时记录一条已删除的快照，回放历史时从这一天起不再计入该任务
+func recordTaskSnapshot(projectID uint, task *Task, deleted bool, now time.Time) {
+	snapshot := newTaskSnapshot(projectID, *task, dateOnly(now))
+	if deleted {
+		snapshot.Work, snapshot.Deleted = 0, true
+	}
+	if err := DB.Clauses(snapshotUpsert).Create(&snapshot).Error; err != nil {
+		log.Printf("记录任务 %d 的进度快照失败: %v", task.ID, err)
+	}
+}
+
+// 某一天的工作量合计
+type workTotals struct {
+	Scope     float64 // 总工作量
+	Remaining float64 // 剩余工作量
+}
+
+func (w *workTotals) add(work, progress float64) {
+	w.Scope += work
+	w.Remaining += work * (1 - progress/100)
+}
+
+// 按当前任务计算工作量
+func liveWorkTotals(tasks []Task) workTotals {
+	var totals workTotals
+	for _, task := range tasks {
+		totals.add(taskWork(task), task.Progress)
+	}
+	return totals
+}
+
+// 读取满足 where 条件的任务在 start 到 end 之间的快照，并补上每个任务在 start 当天及之前最近的一条快照，
+// 使开始日期就能计入更早记录过的任务
+func loadSnapshots(start, end time.Time, where string, args ...interface{}) ([]TaskSnapshot, error) {
+	var snapshots []TaskSnapshot
+	seedArgs := append(append([]interface{}{}, args...), start)
+	if err := DB.Raw("SELECT DISTINCT ON (task_id) * FROM task_snapshots WHERE ("+where+") AND date <= ? ORDER BY task_id, date DESC",
+		seedArgs...).Scan(&snapshots).Error; err != nil {
+		return nil, err
+	}
+
+	var later []TaskSnapshot
+	if err := DB.Where(where, args...).Where("date > ? AND date <= ?", start, end).Find(&later).Error; err != nil {
+		return nil, err
+	}
+	return append(snapshots, later...), nil
+}
+
+// 根据快照回放每个日期的工作量，每个任务取该日期及之前最近的一条快照
+// include 为空时计入所有任务，否则只计入最近一条快照满足条件的任务（如仍在该阶段）；已删除的任务不再计入
+// 第一条快照之前的日期没有数据，返回 nil
+func replaySnapshots(snapshots []TaskSnapshot, dates []time.Time, include func(TaskSnapshot) bool) []*workTotals {
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].Date.Before(snapshots[j].Date) })
+
+	result := make([]*workTotals, len(dates))
+	latest := map[uint]TaskSnapshot{}
+	next := 0
+	for i, date := range dates {
+		for next < len(snapshots) && !dateOnly(snapshots[next].Date).After(date) {
+			latest[snapshots[next].TaskID] = snapshots[next]
+			next++
+		}
+		if len(latest) == 0 {
+			continue
+		}
+		totals := &workTotals{}
+		for _, snapshot := range latest {
+			if snapshot.Deleted || (include != nil && !include(snapshot)) {
+				continue
+			}
+			totals.add(snapshot.Work, snapshot.Progress)
+		}
+		result[i] = totals
+	}
+	return result
+}
+
+// start 到 end（含两端）的每一天
+func dailyDates(start, end time.Time) []time.Time {
+	var dates []time.Time
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		dates = append(dates, date)
+	}
+	return dates
+}
+
+// 燃尽图上的一天，今天之后没有实际值
+type burndownPoint struct {
+	Date      string   `json:"date"`
+	Ideal     float64  `json:"ideal"`
+	Remaining *float64 `json:"remaining"`
+	Scope     *float64 `json:"scope"`
+}
+
+// 阶段燃尽图：每天的剩余工作量和理想燃尽线，工作量单位为任务工期的工作日数
+// 历史数据来自每日进度快照，今天使用任务的当前进度
+func getStageBurndown(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var stage Stage
+	if err := DB.Preload("Tasks").First(&stage, id).Error; err != nil {
+		respondError(c, dbError(err, "STAGE_NOT_FOUND"))
+		return
+	}
+
+	today := dateOnly(time.Now())
+	start, end := dateOnly(stage.StartDate), dateOnly(stage.EndDate)
+	// 阶段逾期未完成时延伸到今天
+	if stage.Status != "completed" && today.After(end) {
+		end = today
+	}
+
+	// 包括曾经属于该阶段的任务，回放时只计入当天仍在该阶段的任务
+	snapshots, err := loadSnapshots(start, end, "task_id IN (SELECT task_id FROM task_snapshots WHERE stage_id = ?)", stage.ID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	live := liveWorkTotals(stage.Tasks)
+	dates := dailyDates(start, end)
+	history := replaySnapshots(snapshots, dates, func(snapshot TaskSnapshot) bool { return snapshot.StageID == stage.ID })
+	plannedDays := calculateWorkDays(stage.StartDate, stage.EndDate)
+
+	series := make([]burndownPoint, 0, len(dates))
+	for i, date := range dates {
+		point := burndownPoint{Date: date.Format("2006-01-02"), Ideal: live.Scope}
+		if plannedDays > 0 {
+			point.Ideal = live.Scope * (1 - plannedFraction(start, dateOnly(stage.EndDate), date))
+		}
+		totals := history[i]
+		if date.Equal(today) {
+			totals = &live
+		}
+		if totals != nil && !date.After(today) {
+			point.Remaining, point.Scope = &totals.Remaining, &totals.Scope
+		}
+		series = append(series, point)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stage_id":   stage.ID,
+		"name":       stage.Name,
+		"start_date": start.Format("2006-01-02"),
+		"end_date":   dateOnly(stage.EndDate).Format("2006-01-02"),
+		"total_work": live.Scope,
+		"remaining":  live.Remaining,
+		"series":     series,
+	})
+}
+
+// 燃起图上的一天，今天之后没有实际值
+type burnupPoint struct {
+	Date      string   `json:"date"`
+	Scope     *float64 `json:"scope"`
+	Completed *float64 `json:"completed"`
+}
+
+// 项目燃起图：每天的总工作量和已完成工作量
+func getProjectBurnup(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var project Project
+	if err := DB.Preload("Stages.Tasks").First(&project, id).Error; err != nil {
+		respondError(c, dbError(err, "PROJECT_NOT_FOUND"))
+		return
+	}
+
+	today := dateOnly(time.Now())
+	start, end := dateOnly(project.StartDate), dateOnly(project.EndDate)
+	if project.Status != "completed" && today.After(end) {
+		end = today
+	}
+
+	snapshots, err := loadSnapshots(start, end, "project_id = ?", project.ID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var tasks []Task
+	for _, stage := range project.Stages {
+		tasks = append(tasks, stage.Tasks...)
+	}
+	live := liveWorkTotals(tasks)
+	dates := dailyDates(start, end)
+	history := replaySnapshots(snapshots, dates, nil)
+
+	series := make([]burnupPoint, 0, len(dates))
+	for i, date := range dates {
+		point := burnupPoint{Date: date.Format("2006-01-02")}
+		totals := history[i]
+		if date.Equal(today) {
+			totals = &live
+		}
+		if totals != nil && !date.After(today) {
+			completed := totals.Scope - totals.Remaining
+			point.Scope, point.Completed = &totals.Scope, &completed
+		}
+		series = append(series, point)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"project_id": project.ID,
+		"name":       project.Name,
+		"start_date": start.Format("2006-01-02"),
+		"end_date":   dateOnly(project.EndDate).Format("2006-01-02"),
+		"total_work": live.Scope,
+		"completed":  live.Scope - live.Remaining,
+		"series":     series,
+	})
+}
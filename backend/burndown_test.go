@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplaySnapshots(t *testing.T) {
+	dates := dailyDates(testDate("2024-03-04"), testDate("2024-03-08"))
+	snap := func(taskID, stageID uint, date string, work, progress float64) TaskSnapshot {
+		return TaskSnapshot{TaskID: taskID, StageID: stageID, Date: testDate(date), Work: work, Progress: progress}
+	}
+	deleted := func(taskID, stageID uint, date string) TaskSnapshot {
+		s := snap(taskID, stageID, date, 0, 0)
+		s.Deleted = true
+		return s
+	}
+	inStage := func(stageID uint) func(TaskSnapshot) bool {
+		return func(s TaskSnapshot) bool { return s.StageID == stageID }
+	}
+
+	tests := []struct {
+		name      string
+		snapshots []TaskSnapshot
+		include   func(TaskSnapshot) bool
+		want      []*workTotals // nil 表示没有数据
+	}{
+		{
+			name:      "第一条快照之前没有数据，之后沿用最近的快照",
+			snapshots: []TaskSnapshot{snap(1, 1, "2024-03-06", 4, 50), snap(1, 1, "2024-03-08", 4, 100)},
+			want:      []*workTotals{nil, nil, {4, 2}, {4, 2}, {4, 0}},
+		},
+		{
+			name:      "开始日期之前的快照从第一天起计入",
+			snapshots: []TaskSnapshot{snap(1, 1, "2024-02-20", 5, 0), snap(2, 1, "2024-03-05", 3, 0)},
+			want:      []*workTotals{{5, 5}, {8, 8}, {8, 8}, {8, 8}, {8, 8}},
+		},
+		{
+			name:      "删除的任务从删除当天起不再计入",
+			snapshots: []TaskSnapshot{snap(1, 1, "2024-03-04", 5, 20), snap(2, 1, "2024-03-04", 3, 0), deleted(1, 1, "2024-03-06")},
+			want:      []*workTotals{{8, 7}, {8, 7}, {3, 3}, {3, 3}, {3, 3}},
+		},
+		{
+			name:      "移到其他阶段的任务不再计入原阶段",
+			snapshots: []TaskSnapshot{snap(1, 1, "2024-03-04", 5, 0), snap(2, 1, "2024-03-04", 3, 0), snap(1, 2, "2024-03-07", 5, 0)},
+			include:   inStage(1),
+			want:      []*workTotals{{8, 8}, {8, 8}, {8, 8}, {3, 3}, {3, 3}},
+		},
+		{
+			name:      "移入的任务从移入当天起计入新阶段",
+			snapshots: []TaskSnapshot{snap(1, 1, "2024-03-04", 5, 0), snap(1, 2, "2024-03-07", 5, 40)},
+			include:   inStage(2),
+			want:      []*workTotals{{0, 0}, {0, 0}, {0, 0}, {5, 3}, {5, 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := replaySnapshots(tt.snapshots, dates, tt.include)
+			for i, date := range dates {
+				want := tt.want[i]
+				switch {
+				case want == nil && got[i] != nil:
+					t.Errorf("%s: got %+v, want nil", date.Format("2006-01-02"), *got[i])
+				case want != nil && got[i] == nil:
+					t.Errorf("%s: got nil, want %+v", date.Format("2006-01-02"), *want)
+				case want != nil && *got[i] != *want:
+					t.Errorf("%s: got %+v, want %+v", date.Format("2006-01-02"), *got[i], *want)
+				}
+			}
+		})
+	}
+}
+
+func TestDailyDates(t *testing.T) {
+	dates := dailyDates(testDate("2024-02-28"), testDate("2024-03-01"))
+	want := []time.Time{testDate("2024-02-28"), testDate("2024-02-29"), testDate("2024-03-01")}
+	if len(dates) != len(want) {
+		t.Fatalf("dailyDates() 返回 %d 天, want %d", len(dates), len(want))
+	}
+	for i := range want {
+		if !dates[i].Equal(want[i]) {
+			t.Errorf("dates[%d] = %s, want %s", i, dates[i], want[i])
+		}
+	}
+}
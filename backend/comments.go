@@ -0,0 +1,308 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 评论可修改的字段
+func commentPatchFields(comment *Comment) map[string]patchSetter {
+	return map[string]patchSetter{
+		"body": func(fe *fieldErrors, f string, v interface{}) { comment.Body = fe.asString(f, v) },
+	}
+}
+
+// 校验评论：必须且只能挂在一个任务或阶段下，作者必须是同一项目的成员
+// 校验通过后根据任务或阶段填写 ProjectID
+func validateComment(comment *Comment) error {
+	var fe fieldErrors
+	if strings.TrimSpace(comment.Body) == "" {
+		fe.add("body", "FIELD_REQUIRED")
+	}
+
+	var projectID uint
+	switch {
+	case comment.TaskID == nil && comment.StageID == nil:
+		fe.add("task_id", "COMMENT_TARGET_REQUIRED")
+	case comment.TaskID != nil && comment.StageID != nil:
+		fe.add("stage_id", "COMMENT_TARGET_REQUIRED")
+	case comment.TaskID != nil:
+		var task Task
+		found, err := fe.reference(DB, "task_id", *comment.TaskID, &task)
+		if err != nil {
+			return err
+		}
+		if found {
+			if err := DB.Model(&Stage{}).Where("id = ?", task.StageID).Pluck("project_id", &projectID).Error; err != nil {
+				return err
+			}
+		}
+	default:
+		var stage Stage
+		found, err := fe.reference(DB, "stage_id", *comment.StageID, &stage)
+		if err != nil {
+			return err
+		}
+		if found {
+			projectID = stage.ProjectID
+		}
+	}
+
+	var author TeamMember
+	authorFound, err := fe.reference(DB, "author_id", comment.AuthorID, &author)
+	if err != nil {
+		return err
+	}
+	if authorFound && projectID != 0 && author.ProjectID != projectID {
+		fe.add("author_id", "AUTHOR_NOT_IN_PROJECT")
+	}
+
+	if err := fe.toError(); err != nil {
+		return err
+	}
+	comment.ProjectID = projectID
+	return nil
+}
+
+// 从正文中解析 @成员姓名，姓名有包含关系时取最长的匹配
+func parseMentions(body string, members []TeamMember) []TeamMember {
+	candidates := append([]TeamMember(nil), members...)
+	sort.SliceStable(candidates, func(i, j int) bool { return len(candidates[i].Name) > len(candidates[j].Name) })
+
+	var mentions []TeamMember
+	seen := map[uint]bool{}
+	for rest := body; ; {
+		i := strings.Index(rest, "@")
+		if i < 0 {
+			break
+		}
+		rest = rest[i+1:]
+		for _, member := range candidates {
+			if member.Name != "" && strings.HasPrefix(rest, member.Name) {
+				if !seen[member.ID] {
+					seen[member.ID] = true
+					mentions = append(mentions, member)
+				}
+				rest = rest[len(member.Name):]
+				break
+			}
+		}
+	}
+	return mentions
+}
+
+// 根据正文更新评论提及的成员
+func updateMentions(tx *gorm.DB, comment *Comment) error {
+	var members []TeamMember
+	if err := tx.Where("project_id = ? AND is_active = ?", comment.ProjectID, true).Find(&members).Error; err != nil {
+		return err
+	}
+	comment.Mentions = parseMentions(comment.Body, members)
+	return tx.Model(comment).Association("Mentions").Replace(comment.Mentions)
+}
+
+// 通知被提及的成员，编辑评论时只通知新增的提及，作者提及自己时不通知
+func notifyMentions(comment *Comment) {
+	var subject string
+	if comment.TaskID != nil {
+		DB.Model(&Task{}).Where("id = ?", *comment.TaskID).Pluck("name", &subject)
+	} else {
+		DB.Model(&Stage{}).Where("id = ?", *comment.StageID).Pluck("name", &subject)
+	}
+
+	var created []Notification
+	for _, member := range comment.Mentions {
+		if member.ID == comment.AuthorID {
+			continue
+		}
+		n := Notification{MemberID: member.ID, ProjectID: comment.ProjectID, TaskID: comment.TaskID, StageID: comment.StageID,
+			Type: "comment_mention", Subject: subject, DueDate: dateOnly(comment.CreatedAt)}
+		if recordNotification(&n, comment.ID) {
+			created = append(created, n)
+		}
+	}
+	if len(created) > 0 {
+		go emailNotifications(created)
+	}
+}
+
+// 评论相关接口
+func getTaskComments(c *gin.Context) {
+	listComments(c, "taskId", "task_id", &Task{}, "TASK_NOT_FOUND")
+}
+
+func getStageComments(c *gin.Context) {
+	listComments(c, "stageId", "stage_id", &Stage{}, "STAGE_NOT_FOUND")
+}
+
+// 按时间顺序列出任务或阶段下的评论
+func listComments(c *gin.Context, param, column string, parent interface{}, notFoundCode string) {
+	id, err := parseIDParam(c, param)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := DB.Select("id").First(parent, id).Error; err != nil {
+		respondError(c, dbError(err, notFoundCode))
+		return
+	}
+
+	var comments []Comment
+	if err := DB.Where(column+" = ?", id).Preload("Author").Preload("Mentions").
+		Order("created_at").Order("id").Find(&comments).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+func createComment(c *gin.Context) {
+	var comment Comment
+	fields := commentPatchFields(&comment)
+	fields["task_id"] = func(fe *fieldErrors, f string, v interface{}) { comment.TaskID = optionalID(fe.asID(f, v)) }
+	fields["stage_id"] = func(fe *fieldErrors, f string, v interface{}) { comment.StageID = optionalID(fe.asID(f, v)) }
+	fields["author_id"] = func(fe *fieldErrors, f string, v interface{}) { comment.AuthorID = fe.asID(f, v) }
+	if err := bindCreateRequest(c, fields); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := validateComment(&comment); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Mentions").Create(&comment).Error; err != nil {
+			return err
+		}
+		return updateMentions(tx, &comment)
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := DB.Preload("Author").First(&comment, comment.ID).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	publishChange("created", &comment)
+	notifyMentions(&comment)
+	c.JSON(http.StatusCreated, comment)
+}
+
+func updateComment(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var comment Comment
+	if err := DB.First(&comment, id).Error; err != nil {
+		respondError(c, dbError(err, "COMMENT_NOT_FOUND"))
+		return
+	}
+	before := comment
+
+	patch, err := decodeMergePatch(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := applyMergePatch(patch, commentPatchFields(&comment), false); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := validateComment(&comment); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	now := time.Now()
+	comment.EditedAt = &now
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Mentions").Save(&comment).Error; err != nil {
+			return err
+		}
+		return updateMentions(tx, &comment)
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := DB.Preload("Author").First(&comment, comment.ID).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	publishUpdate(&before, &comment)
+	notifyMentions(&comment)
+	c.JSON(http.StatusOK, comment)
+}
+
+func deleteComment(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var comment Comment
+	if err := DB.First(&comment, id).Error; err != nil {
+		respondError(c, dbError(err, "COMMENT_NOT_FOUND"))
+		return
+	}
+
+	if err := DB.Select("Mentions").Delete(&comment).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	publishChange("deleted", &comment)
+	respondMessage(c, http.StatusOK, "COMMENT_DELETED")
+}
+
+// 统计项目内每个任务和阶段的评论数
+type commentCounts struct {
+	Tasks  map[uint]int64
+	Stages map[uint]int64
+}
+
+func countComments(projectID uint) (commentCounts, error) {
+	counts := commentCounts{Tasks: map[uint]int64{}, Stages: map[uint]int64{}}
+	for column, dest := range map[string]map[uint]int64{"task_id": counts.Tasks, "stage_id": counts.Stages} {
+		var rows []struct {
+			ID    uint
+			Count int64
+		}
+		if err := DB.Model(&Comment{}).Select(column+" AS id, COUNT(*) AS count").
+			Where("project_id = ? AND "+column+" IS NOT NULL", projectID).
+			Group(column).Scan(&rows).Error; err != nil {
+			return counts, err
+		}
+		for _, row := range rows {
+			dest[row.ID] = row.Count
+		}
+	}
+	return counts, nil
+}
+
+// ID为0时返回nil，用于可选的外键
+func optionalID(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
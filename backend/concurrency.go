@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 带乐观锁版本号的实体
+type versioned interface {
+	versionRef() *uint
+}
+
+func (p *Project) versionRef() *uint    { return &p.Version }
+func (s *Stage) versionRef() *uint      { return &s.Version }
+func (t *Task) versionRef() *uint       { return &t.Version }
+func (m *TeamMember) versionRef() *uint { return &m.Version }
+
+// 版本号对应的ETag
+func versionETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+func setETag(c *gin.Context, version uint) {
+	c.Header("ETag", versionETag(version))
+}
+
+// 检查 If-Match 请求头：缺少时返回428，与当前版本不一致时返回412并附带服务器当前数据
+func checkIfMatch(c *gin.Context, version uint, current interface{}) error {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return newAPIError(http.StatusPreconditionRequired, "IF_MATCH_REQUIRED")
+	}
+
+	etag := versionETag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return nil
+		}
+	}
+	return versionConflict(current)
+}
+
+func versionConflict(current interface{}) *APIError {
+	return newAPIError(http.StatusPreconditionFailed, "VERSION_CONFLICT").WithDetails(gin.H{"current": current})
+}
+
+// 按版本号保存：只有数据库中的版本仍是读取时的版本才写入，同时版本号加1
+// 期间已被其他请求修改时重新读取实体并返回412
+func saveVersioned(db *gorm.DB, entity versioned) error {
+	version := entity.versionRef()
+	expected := *version
+	*version = expected + 1
+
+	result := db.Model(entity).Where("version = ?", expected).Select("*").Omit(clause.Associations).Updates(entity)
+	if result.Error != nil {
+		*version = expected
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if err := db.First(entity).Error; err != nil {
+			return err
+		}
+		return versionConflict(entity)
+	}
+	return nil
+}
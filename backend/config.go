@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -16,6 +17,32 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	DBSSLMode  string
+
+	EventBackend string // memory 或 postgres（多实例部署时使用 LISTEN/NOTIFY 同步事件）
+
+	// 截止提醒
+	ReminderDays int // 提前多少个工作日提醒
+	ReminderHour int // 每天检查的时间（服务器本地时间的小时）
+
+	// 邮件，SMTPHost 为空时不发送邮件
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+	SMTPFrom     string
+	MailLocale   string
+
+	// 附件存储：local 或 s3
+	StorageBackend      string
+	StorageDir          string
+	S3Endpoint          string
+	S3Region            string
+	S3Bucket            string
+	S3AccessKey         string
+	S3SecretKey         string
+	S3PathStyle         bool
+	AttachmentMaxSizeMB int
+	AttachmentTypes     string // 允许的MIME类型，逗号分隔，支持 image/* 通配
 }
 
 func LoadConfig() *Config {
@@ -32,6 +59,29 @@ func LoadConfig() *Config {
 		DBPassword: getEnv("DB_PASSWORD", "password"),
 		DBName:     getEnv("DB_NAME", "gantt_excel"),
 		DBSSLMode:  getEnv("DB_SSLMODE", "disable"),
+
+		EventBackend: getEnv("EVENT_BACKEND", "memory"),
+
+		ReminderDays: getEnvInt("REMINDER_DAYS", 2),
+		ReminderHour: getEnvInt("REMINDER_HOUR", 9),
+
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUser:     getEnv("SMTP_USER", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", ""),
+		MailLocale:   getEnv("MAIL_LOCALE", defaultLocale),
+
+		StorageBackend:      getEnv("STORAGE_BACKEND", "local"),
+		StorageDir:          getEnv("STORAGE_DIR", "./uploads"),
+		S3Endpoint:          getEnv("S3_ENDPOINT", ""),
+		S3Region:            getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:            getEnv("S3_BUCKET", ""),
+		S3AccessKey:         getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:         getEnv("S3_SECRET_KEY", ""),
+		S3PathStyle:         getEnv("S3_PATH_STYLE", "true") == "true",
+		AttachmentMaxSizeMB: getEnvInt("ATTACHMENT_MAX_SIZE_MB", 20),
+		AttachmentTypes:     getEnv("ATTACHMENT_TYPES", defaultAttachmentTypes),
 	}
 }
 
@@ -46,3 +96,16 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("环境变量 %s 不是有效的整数，使用默认值 %d", key, defaultValue)
+		return defaultValue
+	}
+	return n
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 计划成本和实际成本的上限
+const maxCost = 1000000000000
+
+// 成本汇总：计划成本、实际成本、偏差（计划-实际）以及是否超支
+// 只有设置了计划成本时才判断超支
+type costSummary struct {
+	PlannedCost float64 `json:"planned_cost"`
+	ActualCost  float64 `json:"actual_cost"`
+	Variance    float64 `json:"variance"`
+	OverBudget  bool    `json:"over_budget"`
+}
+
+func (s *costSummary) add(other costSummary) {
+	s.PlannedCost += other.PlannedCost
+	s.ActualCost += other.ActualCost
+}
+
+func (s *costSummary) finish() {
+	s.Variance = s.PlannedCost - s.ActualCost
+	s.OverBudget = s.PlannedCost > 0 && s.ActualCost > s.PlannedCost
+}
+
+type taskCost struct {
+	TaskID uint   `json:"task_id"`
+	Name   string `json:"name"`
+	// 实际成本来源：manual 为手工填写，time_entries 为按工时记录计算
+	ActualSource string `json:"actual_source"`
+	costSummary
+}
+
+type stageCost struct {
+	StageID uint       `json:"stage_id"`
+	Name    string     `json:"name"`
+	Tasks   []taskCost `json:"tasks"`
+	costSummary
+}
+
+type costReport struct {
+	ProjectID uint        `json:"project_id"`
+	Stages    []stageCost `json:"stages"`
+	costSummary
+}
+
+// 项目成本报表，按阶段和任务汇总
+func getProjectCosts(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var project Project
+	if err := DB.Scopes(preloadStageTree).First(&project, id).Error; err != nil {
+		respondError(c, dbError(err, "PROJECT_NOT_FOUND"))
+		return
+	}
+
+	report, err := computeProjectCosts(project)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// 计算项目成本，任务的实际成本手工填写时优先，否则为工时记录乘以记录成员的小时费率
+func computeProjectCosts(project Project) (*costReport, error) {
+	laborCosts, err := sumTaskLaborCosts(project.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &costReport{ProjectID: project.ID, Stages: []stageCost{}}
+	for _, stage := range project.Stages {
+		sc := stageCost{StageID: stage.ID, Name: stage.Name, Tasks: []taskCost{}}
+		for _, task := range stage.Tasks {
+			tc := taskCost{TaskID: task.ID, Name: task.Name, ActualSource: "time_entries"}
+			tc.PlannedCost = task.PlannedCost
+			tc.ActualCost = laborCosts[task.ID]
+			if task.ActualCost != nil {
+				tc.ActualCost = *task.ActualCost
+				tc.ActualSource = "manual"
+			}
+			tc.finish()
+			sc.add(tc.costSummary)
+			sc.Tasks = append(sc.Tasks, tc)
+		}
+		sc.finish()
+		report.add(sc.costSummary)
+		report.Stages = append(report.Stages, sc)
+	}
+	report.finish()
+	return report, nil
+}
+
+// 统计项目内每个任务按工时记录计算的人工成本
+func sumTaskLaborCosts(projectID uint) (map[uint]float64, error) {
+	var rows []struct {
+		TaskID uint
+		Cost   float64
+	}
+	if err := DB.Model(&TimeEntry{}).Select("time_entries.task_id, SUM(time_entries.hours * team_members.hourly_rate) AS cost").
+		Joins("JOIN team_members ON team_members.id = time_entries.member_id").
+		Where("time_entries.project_id = ?", projectID).Group("time_entries.task_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	costs := make(map[uint]float64, len(rows))
+	for _, row := range rows {
+		costs[row.TaskID] = row.Cost
+	}
+	return costs, nil
+}
@@ -27,6 +27,15 @@ func InitDatabase(config *Config) {
 		&Task{},
 		&TeamMember{},
 		&Role{},
+		&ExportTemplate{},
+		&Webhook{},
+		&WebhookDelivery{},
+		&Notification{},
+		&Comment{},
+		&Attachment{},
+		&TimeEntry{},
+		&TaskSnapshot{},
+		&SavedView{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
@@ -34,8 +43,14 @@ func InitDatabase(config *Config) {
 
 	log.Println("Database migration completed")
 
+	// 创建搜索索引
+	initSearchIndexes()
+
 	// 初始化默认角色
 	initDefaultRoles()
+
+	// 初始化默认导出模板
+	initDefaultExportTemplates()
 }
 
 func initDefaultRoles() {
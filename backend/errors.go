@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// PostgreSQL错误码
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgCheckViolation      = "23514"
+	pgNotNullViolation    = "23502"
+	pgInvalidText         = "22P02"
+)
+
+// API错误：Code 为稳定的错误码，信息在响应时按请求语言翻译
+type APIError struct {
+	Status  int
+	Code    string
+	Args    []interface{}
+	Details interface{}
+	Fields  []FieldError
+	Cause   error // 内部原因，只记录日志不返回给客户端
+}
+
+// 字段级错误
+type FieldError struct {
+	Field   string        `json:"field"`
+	Code    string        `json:"code"`
+	Message string        `json:"message"`
+	Args    []interface{} `json:"-"`
+}
+
+func newAPIError(status int, code string, args ...interface{}) *APIError {
+	return &APIError{Status: status, Code: code, Args: args}
+}
+
+func badRequest(code string, args ...interface{}) *APIError {
+	return newAPIError(http.StatusBadRequest, code, args...)
+}
+
+func notFound(code string) *APIError {
+	return newAPIError(http.StatusNotFound, code)
+}
+
+func internalError(code string, cause error) *APIError {
+	return &APIError{Status: http.StatusInternalServerError, Code: code, Cause: cause}
+}
+
+func (e *APIError) Error() string {
+	msg := T(defaultLocale, e.Code, e.Args...)
+	if e.Cause != nil {
+		msg += ": " + e.Cause.Error()
+	}
+	return msg
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Cause
+}
+
+func (e *APIError) WithDetails(details interface{}) *APIError {
+	e.Details = details
+	return e
+}
+
+// 将数据库错误转换为API错误，记录不存在时使用 notFoundCode
+func dbError(err error, notFoundCode string) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if notFoundCode == "" {
+			notFoundCode = "RESOURCE_NOT_FOUND"
+		}
+		return notFound(notFoundCode)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			return &APIError{Status: http.StatusConflict, Code: "DUPLICATE_RESOURCE", Cause: err,
+				Details: gin.H{"constraint": pgErr.ConstraintName}}
+		case pgForeignKeyViolation:
+			// 删除或更新仍被引用的记录时为冲突，插入引用不存在的记录时为无法处理的请求
+			if strings.HasPrefix(pgErr.Message, "update or delete") {
+				return &APIError{Status: http.StatusConflict, Code: "RESOURCE_IN_USE", Cause: err,
+					Details: gin.H{"constraint": pgErr.ConstraintName}}
+			}
+			return &APIError{Status: http.StatusUnprocessableEntity, Code: "REFERENCED_RESOURCE_NOT_FOUND", Cause: err,
+				Details: gin.H{"constraint": pgErr.ConstraintName}}
+		case pgCheckViolation, pgNotNullViolation:
+			return &APIError{Status: http.StatusUnprocessableEntity, Code: "CONSTRAINT_VIOLATION", Cause: err,
+				Details: gin.H{"constraint": pgErr.ConstraintName, "column": pgErr.ColumnName}}
+		case pgInvalidText:
+			return &APIError{Status: http.StatusBadRequest, Code: "INVALID_PARAMETER", Cause: err}
+		}
+	}
+
+	return internalError("DATABASE_ERROR", err)
+}
+
+// 将请求体绑定错误转换为API错误
+func bindError(err error) *APIError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		apiErr := &APIError{Status: http.StatusUnprocessableEntity, Code: "VALIDATION_FAILED"}
+		for _, fe := range validationErrs {
+			apiErr.Fields = append(apiErr.Fields, FieldError{Field: fe.Field(), Code: "INVALID_FIELD"})
+		}
+		return apiErr
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &APIError{Status: http.StatusBadRequest, Code: "INVALID_REQUEST_BODY",
+			Fields: []FieldError{{Field: typeErr.Field, Code: "INVALID_TYPE", Args: []interface{}{typeErr.Type.String()}}}}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return badRequest("INVALID_REQUEST_BODY").WithDetails(gin.H{"offset": syntaxErr.Offset})
+	}
+
+	return badRequest("INVALID_REQUEST_BODY")
+}
+
+// 解析路径中的ID参数
+func parseIDParam(c *gin.Context, name string) (uint, error) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil || id == 0 {
+		return 0, badRequest("INVALID_ID", name)
+	}
+	return uint(id), nil
+}
+
+// 返回错误响应，非APIError按数据库错误处理
+func respondError(c *gin.Context, err error) {
+	apiErr := dbError(err, "")
+	if apiErr.Cause != nil {
+		log.Printf("%s %s 请求失败 [%s]: %v", c.Request.Method, c.Request.URL.Path, apiErr.Code, apiErr.Cause)
+	}
+	c.AbortWithStatusJSON(apiErr.Status, errorBody(getLocale(c), apiErr))
+}
+
+// 按语言生成错误响应体
+func errorBody(locale string, apiErr *APIError) gin.H {
+	body := gin.H{"code": apiErr.Code, "error": T(locale, apiErr.Code, apiErr.Args...)}
+	if apiErr.Details != nil {
+		body["details"] = apiErr.Details
+	}
+	if len(apiErr.Fields) > 0 {
+		fields := make([]FieldError, len(apiErr.Fields))
+		for i, fe := range apiErr.Fields {
+			fe.Message = T(locale, fe.Code, fe.Args...)
+			fields[i] = fe
+		}
+		body["fields"] = fields
+	}
+	return body
+}
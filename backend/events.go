@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// 项目事件，推送给订阅该项目的客户端
+type Event struct {
+	Type      string      `json:"type"`   // 如 task.updated
+	Entity    string      `json:"entity"` // project, stage, task, member, comment, attachment, time_entry
+	Action    string      `json:"action"` // created, updated, deleted, reordered, completed, deadline_slipped
+	ProjectID uint        `json:"project_id"`
+	EntityID  uint        `json:"entity_id,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	Previous  interface{} `json:"previous,omitempty"` // 更新前的数据
+	Time      time.Time   `json:"time"`
+}
+
+// 事件代理：进程内分发，或通过 PostgreSQL LISTEN/NOTIFY 在多个后端实例间同步
+type eventBroker interface {
+	Publish(event Event)
+	Subscribe(projectID uint) (<-chan Event, func())
+}
+
+var Events eventBroker = newLocalBroker()
+
+// 每个订阅者的缓冲区大小，客户端处理不过来时丢弃事件
+const eventBufferSize = 64
+
+// 进程内事件代理
+type localBroker struct {
+	mu          sync.RWMutex
+	subscribers map[uint]map[chan Event]struct{}
+}
+
+func newLocalBroker() *localBroker {
+	return &localBroker{subscribers: make(map[uint]map[chan Event]struct{})}
+}
+
+func (b *localBroker) Publish(event Event) {
+	b.deliver(event)
+}
+
+func (b *localBroker) deliver(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subscribers[event.ProjectID] {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("项目 %d 的事件订阅者处理过慢，丢弃事件 %s", event.ProjectID, event.Type)
+		}
+	}
+}
+
+func (b *localBroker) Subscribe(projectID uint) (<-chan Event, func()) {
+	ch := make(chan Event, eventBufferSize)
+
+	b.mu.Lock()
+	if b.subscribers[projectID] == nil {
+		b.subscribers[projectID] = make(map[chan Event]struct{})
+	}
+	b.subscribers[projectID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers[projectID], ch)
+			if len(b.subscribers[projectID]) == 0 {
+				delete(b.subscribers, projectID)
+			}
+			b.mu.Unlock()
+		})
+	}
+}
+
+// PostgreSQL 事件通道名称和 NOTIFY 负载长度上限
+const (
+	pgEventChannel    = "gantt_events"
+	pgMaxNotifyLength = 7900
+)
+
+// 基于 LISTEN/NOTIFY 的事件代理：发布时发送 NOTIFY，所有实例（包括自身）收到后再分发给本地订阅者
+type pgBroker struct {
+	*localBroker
+	connString string
+}
+
+func newPgBroker(connString string) *pgBroker {
+	b := &pgBroker{localBroker: newLocalBroker(), connString: connString}
+	go b.listen()
+	return b
+}
+
+func (b *pgBroker) Publish(event Event) {
+	payload, err := json.Marshal(event)
+	if err == nil && len(payload) > pgMaxNotifyLength {
+		// 超过 NOTIFY 长度限制时只通知变化，客户端自行重新获取数据
+		event.Data, event.Previous = nil, nil
+		payload, err = json.Marshal(event)
+	}
+	if err != nil {
+		log.Printf("序列化事件失败: %v", err)
+		return
+	}
+
+	if err := DB.Exec("SELECT pg_notify(?, ?)", pgEventChannel, string(payload)).Error; err != nil {
+		log.Printf("发送事件通知失败，仅在本实例分发: %v", err)
+		b.deliver(event)
+	}
+}
+
+// 监听通知，连接断开后按指数退避重连
+func (b *pgBroker) listen() {
+	backoff := time.Second
+	for {
+		err := b.listenOnce()
+		log.Printf("事件监听连接断开，%v 后重连: %v", backoff, err)
+		time.Sleep(backoff)
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+func (b *pgBroker) listenOnce() error {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, b.connString)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgEventChannel); err != nil {
+		return err
+	}
+	log.Printf("已开始监听事件通道 %s", pgEventChannel)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var event Event
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			log.Printf("解析事件通知失败: %v", err)
+			continue
+		}
+		b.deliver(event)
+	}
+}
+
+// 根据配置初始化事件代理
+func InitEvents(config *Config) {
+	if config.EventBackend == "postgres" {
+		Events = newPgBroker(config.GetDBConnectionString())
+		log.Println("事件代理使用 PostgreSQL LISTEN/NOTIFY")
+		return
+	}
+	log.Println("事件代理使用进程内分发")
+}
+
+// 发布实体变更事件，任务所属项目通过阶段查询
+func publishChange(action string, entity interface{}) {
+	publishEvent(action, entity, nil)
+}
+
+// 发布更新事件，任务和阶段完成或截止日期推迟时额外发布对应事件
+func publishUpdate(before, after interface{}) {
+	publishEvent("updated", after, before)
+
+	switch a := after.(type) {
+	case *Task:
+		b := before.(*Task)
+		if a.Status == "completed" && b.Status != "completed" {
+			publishEvent("completed", a, b)
+		}
+		if dateOnly(a.EndDate).After(dateOnly(b.EndDate)) {
+			publishEvent("deadline_slipped", a, b)
+		}
+	case *Stage:
+		b := before.(*Stage)
+		if a.Status == "completed" && b.Status != "completed" {
+			publishEvent("completed", a, b)
+		}
+		if dateOnly(a.EndDate).After(dateOnly(b.EndDate)) {
+			publishEvent("deadline_slipped", a, b)
+		}
+	}
+}
+
+func publishEvent(action string, entity, previous interface{}) {
+	event := Event{Action: action, Data: entity, Previous: previous, Time: time.Now()}
+	switch e := entity.(type) {
+	case *Project:
+		event.Entity, event.EntityID, event.ProjectID = "project", e.ID, e.ID
+	case *Stage:
+		event.Entity, event.EntityID, event.ProjectID = "stage", e.ID, e.ProjectID
+	case *TeamMember:
+		event.Entity, event.EntityID, event.ProjectID = "member", e.ID, e.ProjectID
+	case *Comment:
+		event.Entity, event.EntityID, event.ProjectID = "comment", e.ID, e.ProjectID
+	case *Attachment:
+		event.Entity, event.EntityID, event.ProjectID = "attachment", e.ID, e.ProjectID
+	case *TimeEntry:
+		event.Entity, event.EntityID, event.ProjectID = "time_entry", e.ID, e.ProjectID
+	case *Task:
+		event.Entity, event.EntityID = "task", e.ID
+		if err := DB.Model(&Stage{}).Where("id = ?", e.StageID).Pluck("project_id", &event.ProjectID).Error; err != nil {
+			log.Printf("查询任务 %d 所属项目失败: %v", e.ID, err)
+			return
+		}
+	default:
+		return
+	}
+	event.Type = event.Entity + "." + event.Action
+	Events.Publish(event)
+	enqueueWebhooks(event)
+
+	if task, ok := entity.(*Task); ok {
+		previousTask, _ := previous.(*Task)
+		notifyTaskChange(action, event.ProjectID, task, previousTask)
+		recordTaskSnapshot(event.ProjectID, task, action == "deleted", event.Time)
+	}
+}
+
+// 发布排序变更事件
+func publishReorder(entity string, projectID uint, ids []uint) {
+	event := Event{
+		Type:      entity + ".reordered",
+		Entity:    entity,
+		Action:    "reordered",
+		ProjectID: projectID,
+		Data:      ids,
+		Time:      time.Now(),
+	}
+	Events.Publish(event)
+	enqueueWebhooks(event)
+}
+
+// 事件保活间隔，防止代理服务器断开空闲连接
+const eventKeepAlive = 25 * time.Second
+
+// 以 Server-Sent Events 推送项目事件
+func streamProjectEvents(c *gin.Context) {
+	projectID, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := DB.Select("id").First(&Project{}, projectID).Error; err != nil {
+		respondError(c, dbError(err, "PROJECT_NOT_FOUND"))
+		return
+	}
+
+	ch, unsubscribe := Events.Subscribe(projectID)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.SSEvent("ready", gin.H{"project_id": projectID})
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(eventKeepAlive)
+	defer ticker.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-ticker.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
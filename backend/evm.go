@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// 任务未填写预估工时时按工期每个工作日8小时计算预算；成员小时费率上限
+const (
+	defaultHoursPerDay = 8
+	maxHourlyRate      = 100000
+)
+
+// 挣值分析结果，金额单位由 basis 决定：cost 为工时×成员小时费率，hours 为工时
+type evmReport struct {
+	ProjectID  uint     `json:"project_id"`
+	StatusDate string   `json:"status_date"`
+	Basis      string   `json:"basis"`
+	BAC        float64  `json:"bac"` // 完工预算
+	PV         float64  `json:"pv"`  // 计划价值
+	EV         float64  `json:"ev"`  // 挣值
+	AC         float64  `json:"ac"`  // 实际成本
+	SV         float64  `json:"sv"`  // 进度偏差 EV-PV
+	CV         float64  `json:"cv"`  // 成本偏差 EV-AC
+	SPI        *float64 `json:"spi"` // 进度绩效指数 EV/PV，PV为0时为空
+	CPI        *float64 `json:"cpi"` // 成本绩效指数 EV/AC，AC为0时为空
+	EAC        *float64 `json:"eac"` // 完工估算 BAC/CPI
+	ETC        *float64 `json:"etc"` // 完工尚需估算 EAC-AC
+	VAC        *float64 `json:"vac"` // 完工偏差 BAC-EAC
+
+	Series []evmPoint `json:"series"` // S曲线数据
+}
+
+// S曲线上的一个点，EV 和 AC 只在状态日期及之前有值
+type evmPoint struct {
+	Date string   `json:"date"`
+	PV   float64  `json:"pv"`
+	EV   *float64 `json:"ev"`
+	AC   *float64 `json:"ac"`
+}
+
+// 项目挣值分析
+// date 为状态日期（默认今天），basis 为 cost（默认）或 hours，interval 为 week（默认）或 day
+func getProjectEVM(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	statusDate := time.Now()
+	if date := c.Query("date"); date != "" {
+		if statusDate, err = parseDate(date); err != nil {
+			respondError(c, badRequest("INVALID_PARAMETER"))
+			return
+		}
+	}
+	basis := c.DefaultQuery("basis", "cost")
+	interval := c.DefaultQuery("interval", "week")
+	if !containsString([]string{"cost", "hours"}, basis) || !containsString([]string{"week", "day"}, interval) {
+		respondError(c, badRequest("INVALID_PARAMETER"))
+		return
+	}
+
+	var project Project
+	if err := DB.Scopes(preloadStageTree).Preload("Stages.Tasks.Assignee").First(&project, id).Error; err != nil {
+		respondError(c, dbError(err, "PROJECT_NOT_FOUND"))
+		return
+	}
+
+	report, err := computeEVM(project, dateOnly(statusDate), basis, interval)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// 计算项目挣值指标和S曲线
+// 任务预算为计划成本，未填写时为预估工时（未填写时按工期估算）乘以负责人的小时费率，PV 按工作日在任务工期内线性分布，
+// EV 为预算乘以当前进度，AC 与成本报表一致：填写了实际成本的任务使用填写的值，计入任务结束日期与状态日期中较早的一天，
+// 其他任务为工时记录乘以记录成员的小时费率；按工时计算时 AC 总是使用记录的工时
+func computeEVM(project Project, statusDate time.Time, basis, interval string) (*evmReport, error) {
+	type taskBudget struct {
+		start, end time.Time
+		budget     float64
+	}
+	type dailyCost struct {
+		Date time.Time
+		Cost float64
+	}
+	var budgets []taskBudget
+	var costs []dailyCost
+	var manualTaskIDs []uint
+	report := &evmReport{ProjectID: project.ID, StatusDate: statusDate.Format("2006-01-02"), Basis: basis}
+
+	for _, stage := range project.Stages {
+		for _, task := range stage.Tasks {
+			start, end := dateOnly(task.StartDate), dateOnly(task.EndDate)
+			hours := task.EstimatedHours
+			if hours == 0 {
+				hours = float64(calculateWorkDays(start, end) * defaultHoursPerDay)
+			}
+			budget := hours
+			if basis == "cost" {
+				budget = hours * task.Assignee.HourlyRate
+				if task.PlannedCost > 0 {
+					budget = task.PlannedCost
+				}
+			}
+			budgets = append(budgets, taskBudget{start: start, end: end, budget: budget})
+			if basis == "cost" && task.ActualCost != nil {
+				manualTaskIDs = append(manualTaskIDs, task.ID)
+				date := end
+				if date.After(statusDate) {
+					date = statusDate
+				}
+				costs = append(costs, dailyCost{Date: date, Cost: *task.ActualCost})
+			}
+			report.BAC += budget
+			report.EV += budget * task.Progress / 100
+		}
+	}
+
+	// 按日期累计实际成本
+	rateExpr := "team_members.hourly_rate"
+	if basis == "hours" {
+		rateExpr = "1"
+	}
+	query := DB.Model(&TimeEntry{}).Select("time_entries.date, SUM(time_entries.hours * "+rateExpr+") AS cost").
+		Joins("JOIN team_members ON team_members.id = time_entries.member_id").
+		Where("time_entries.project_id = ? AND time_entries.date <= ?", project.ID, statusDate)
+	if len(manualTaskIDs) > 0 {
+		query = query.Where("time_entries.task_id NOT IN ?", manualTaskIDs)
+	}
+	var entryCosts []dailyCost
+	if err := query.Group("time_entries.date").Order("time_entries.date").Scan(&entryCosts).Error; err != nil {
+		return nil, err
+	}
+	costs = append(costs, entryCosts...)
+
+	plannedValue := func(date time.Time) float64 {
+		var pv float64
+		for _, b := range budgets {
+			pv += b.budget * plannedFraction(b.start, b.end, date)
+		}
+		return pv
+	}
+	actualCost := func(date time.Time) float64 {
+		var ac float64
+		for _, cost := range costs {
+			if !dateOnly(cost.Date).After(date) {
+				ac += cost.Cost
+			}
+		}
+		return ac
+	}
+
+	report.PV = plannedValue(statusDate)
+	report.AC = actualCost(statusDate)
+	report.SV = report.EV - report.PV
+	report.CV = report.EV - report.AC
+	if report.PV > 0 {
+		spi := report.EV / report.PV
+		report.SPI = &spi
+	}
+	if report.AC > 0 && report.EV > 0 {
+		cpi := report.EV / report.AC
+		eac := report.BAC / cpi
+		etc := eac - report.AC
+		vac := report.BAC - eac
+		report.CPI, report.EAC, report.ETC, report.VAC = &cpi, &eac, &etc, &vac
+	}
+
+	// S曲线从项目开始到项目结束（状态日期更晚时到状态日期），状态日期总是包含在内
+	start, end := dateOnly(project.StartDate), dateOnly(project.EndDate)
+	if statusDate.After(end) {
+		end = statusDate
+	}
+	step := func(d time.Time) time.Time { return d.AddDate(0, 0, 7) }
+	if interval == "day" {
+		step = func(d time.Time) time.Time { return d.AddDate(0, 0, 1) }
+	}
+	statusAdded := false
+	for date := start; !date.After(end); date = step(date) {
+		if !statusAdded && date.After(statusDate) {
+			report.Series = append(report.Series, evmPointAt(statusDate, statusDate, report.EV, plannedValue, actualCost))
+			statusAdded = true
+		}
+		report.Series = append(report.Series, evmPointAt(date, statusDate, report.EV, plannedValue, actualCost))
+		if date.Equal(statusDate) {
+			statusAdded = true
+		}
+	}
+	if !statusAdded && !statusDate.Before(start) {
+		report.Series = append(report.Series, evmPointAt(statusDate, statusDate, report.EV, plannedValue, actualCost))
+	}
+	return report, nil
+}
+
+// 进度只有当前值，EV 只在状态日期给出；AC 在状态日期及之前给出
+func evmPointAt(date, statusDate time.Time, ev float64, plannedValue, actualCost func(time.Time) float64) evmPoint {
+	point := evmPoint{Date: date.Format("2006-01-02"), PV: plannedValue(date)}
+	if !date.After(statusDate) {
+		ac := actualCost(date)
+		point.AC = &ac
+	}
+	if date.Equal(statusDate) {
+		point.EV = &ev
+	}
+	return point
+}
+
+// 截至 date（含当天）计划完成的比例，按工作日线性计算
+func plannedFraction(start, end, date time.Time) float64 {
+	if start.IsZero() || end.IsZero() || date.Before(start) {
+		return 0
+	}
+	if !date.Before(end) {
+		return 1
+	}
+	total := calculateWorkDays(start, end)
+	if total == 0 {
+		return 0
+	}
+	return float64(calculateWorkDays(start, date)) / float64(total)
+}
+
+// 挣值分析表：上方为状态日期的指标，下方为S曲线数据和折线图
+func (b *workbookBuilder) writeEVMSheet(sheetName string) error {
+	f, tpl := b.f, b.tpl
+
+	report, err := computeEVM(b.project, dateOnly(time.Now()), "cost", "week")
+	if err != nil {
+		return err
+	}
+
+	metrics := []struct {
+		key   string
+		value *float64
+	}{
+		{"bac", &report.BAC}, {"pv", &report.PV}, {"ev", &report.EV}, {"ac", &report.AC},
+		{"sv", &report.SV}, {"cv", &report.CV}, {"spi", report.SPI}, {"cpi", report.CPI},
+		{"eac", report.EAC}, {"etc", report.ETC}, {"vac", report.VAC},
+	}
+	f.SetCellValue(sheetName, "A1", tpl.label("evm.status_date"))
+	f.SetCellValue(sheetName, "B1", report.StatusDate)
+	for i, metric := range metrics {
+		r := strconv.Itoa(i + 2)
+		f.SetCellValue(sheetName, "A"+r, tpl.label("evm."+metric.key))
+		if metric.value != nil {
+			f.SetCellValue(sheetName, "B"+r, *metric.value)
+		}
+	}
+	if err := f.SetCellStyle(sheetName, "A1", "A"+strconv.Itoa(len(metrics)+1), b.headerStyle); err != nil {
+		return err
+	}
+
+	// S曲线数据
+	headerRow := len(metrics) + 3
+	h := strconv.Itoa(headerRow)
+	for i, key := range []string{"date", "pv", "ev", "ac"} {
+		f.SetCellValue(sheetName, getColumnLetter(i+1)+h, tpl.label("evm."+key))
+	}
+	for i, point := range report.Series {
+		r := strconv.Itoa(headerRow + i + 1)
+		date, _ := parseDate(point.Date)
+		setDateCell(f, sheetName, "A"+r, date, b.styles)
+		f.SetCellValue(sheetName, "B"+r, point.PV)
+		if point.EV != nil {
+			f.SetCellValue(sheetName, "C"+r, *point.EV)
+		}
+		if point.AC != nil {
+			f.SetCellValue(sheetName, "D"+r, *point.AC)
+		}
+	}
+	if err := f.SetCellStyle(sheetName, "A"+h, "D"+h, b.headerStyle); err != nil {
+		return err
+	}
+	f.SetColWidth(sheetName, "A", "A", 22)
+	f.SetColWidth(sheetName, "B", "D", 14)
+
+	if len(report.Series) == 0 {
+		return nil
+	}
+	first, last := headerRow+1, headerRow+len(report.Series)
+	quoted := "'" + sheetName + "'"
+	var series []excelize.ChartSeries
+	for _, col := range []string{"B", "C", "D"} {
+		series = append(series, excelize.ChartSeries{
+			Name:       fmt.Sprintf("%s!$%s$%d", quoted, col, headerRow),
+			Categories: fmt.Sprintf("%s!$A$%d:$A$%d", quoted, first, last),
+			Values:     fmt.Sprintf("%s!$%s$%d:$%s$%d", quoted, col, first, col, last),
+		})
+	}
+	return f.AddChart(sheetName, "F2", &excelize.Chart{
+		Type:         excelize.Line,
+		Series:       series,
+		Title:        []excelize.RichTextRun{{Text: tpl.label("evm.chart_title")}},
+		Legend:       excelize.ChartLegend{Position: "bottom"},
+		Dimension:    excelize.ChartDimension{Width: 720, Height: 360},
+		ShowBlanksAs: "gap",
+	})
+}
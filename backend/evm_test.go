@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func testDate(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestCalculateWorkDays(t *testing.T) {
+	tests := []struct {
+		start, end string
+		want       int
+	}{
+		{"2024-03-04", "2024-03-08", 5},
+		{"2024-03-08", "2024-03-11", 2},
+		{"2024-03-09", "2024-03-10", 0},
+		{"2024-03-04", "2024-03-15", 10},
+		{"2024-03-06", "2024-03-06", 1},
+		{"2024-03-08", "2024-03-04", 0},
+	}
+	for _, tt := range tests {
+		if got := calculateWorkDays(testDate(tt.start), testDate(tt.end)); got != tt.want {
+			t.Errorf("calculateWorkDays(%s, %s) = %d, want %d", tt.start, tt.end, got, tt.want)
+		}
+	}
+	if got := calculateWorkDays(time.Time{}, testDate("2024-03-08")); got != 0 {
+		t.Errorf("calculateWorkDays(zero, 2024-03-08) = %d, want 0", got)
+	}
+}
+
+func TestPlannedFraction(t *testing.T) {
+	tests := []struct {
+		name             string
+		start, end, date string
+		want             float64
+	}{
+		{"开始之前", "2024-03-04", "2024-03-08", "2024-03-01", 0},
+		{"开始当天", "2024-03-04", "2024-03-08", "2024-03-04", 0.2},
+		{"进行中", "2024-03-04", "2024-03-08", "2024-03-06", 0.6},
+		{"结束当天", "2024-03-04", "2024-03-08", "2024-03-08", 1},
+		{"结束之后", "2024-03-04", "2024-03-08", "2024-04-01", 1},
+		{"周六不计进度", "2024-03-07", "2024-03-12", "2024-03-09", 0.5},
+		{"周日不计进度", "2024-03-07", "2024-03-12", "2024-03-10", 0.5},
+		{"周末之后", "2024-03-07", "2024-03-12", "2024-03-11", 0.75},
+		{"只在周末的任务", "2024-03-09", "2024-03-10", "2024-03-09", 0},
+		{"只在周末的任务结束", "2024-03-09", "2024-03-10", "2024-03-10", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := plannedFraction(testDate(tt.start), testDate(tt.end), testDate(tt.date))
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("plannedFraction(%s, %s, %s) = %v, want %v", tt.start, tt.end, tt.date, got, tt.want)
+			}
+		})
+	}
+
+	if got := plannedFraction(time.Time{}, testDate("2024-03-08"), testDate("2024-03-06")); got != 0 {
+		t.Errorf("未设置开始日期时 plannedFraction() = %v, want 0", got)
+	}
+}
@@ -0,0 +1,816 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// Excel中使用的单元格格式
+const (
+	excelDateFormat     = "yyyy-mm-dd"
+	excelDayFormat      = "mm/dd"
+	excelPercentFormat  = "0.0%"
+	excelDurationFormat = "0"
+	excelHoursFormat    = "0.0"
+	excelCostFormat     = "#,##0.00;[Red]-#,##0.00"
+)
+
+// 时间线表中日期列之前的固定列：名称、开始、结束、工期、状态
+const timelineFixedCols = 5
+
+// 导出时复用的单元格样式
+type exportStyles struct {
+	Date     int
+	Day      int
+	Percent  int
+	Duration int
+	Hours    int
+	Cost     int
+	OverCost int // 超支的实际成本，红色加粗
+}
+
+func newExportStyles(f *excelize.File) (*exportStyles, error) {
+	newNumFmtStyle := func(format string) (int, error) {
+		return f.NewStyle(&excelize.Style{CustomNumFmt: &format})
+	}
+
+	styles := &exportStyles{}
+	var err error
+	if styles.Date, err = newNumFmtStyle(excelDateFormat); err != nil {
+		return nil, err
+	}
+	if styles.Day, err = newNumFmtStyle(excelDayFormat); err != nil {
+		return nil, err
+	}
+	if styles.Percent, err = newNumFmtStyle(excelPercentFormat); err != nil {
+		return nil, err
+	}
+	if styles.Duration, err = newNumFmtStyle(excelDurationFormat); err != nil {
+		return nil, err
+	}
+	if styles.Hours, err = newNumFmtStyle(excelHoursFormat); err != nil {
+		return nil, err
+	}
+	if styles.Cost, err = newNumFmtStyle(excelCostFormat); err != nil {
+		return nil, err
+	}
+	costFormat := excelCostFormat
+	if styles.OverCost, err = f.NewStyle(&excelize.Style{
+		CustomNumFmt: &costFormat,
+		Font:         &excelize.Font{Bold: true, Color: "C00000"},
+	}); err != nil {
+		return nil, err
+	}
+	return styles, nil
+}
+
+// 导出项目甘特图到Excel
+func exportProjectToExcel(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// 获取导出模板
+	template, err := findExportTemplate(c.Query("template"), getLocale(c))
+	if err != nil {
+		respondError(c, dbError(err, "EXPORT_TEMPLATE_NOT_FOUND"))
+		return
+	}
+
+	// 获取项目信息
+	var project Project
+	if err := DB.Scopes(preloadStageTree).Preload("Stages.Tasks.Assignee").Preload("TeamMembers").First(&project, id).Error; err != nil {
+		respondError(c, dbError(err, "PROJECT_NOT_FOUND"))
+		return
+	}
+	// 按视图或筛选参数只导出匹配的任务
+	if err := filterProjectTasks(c, &project); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// 创建Excel文件
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("关闭Excel文件失败: %v", err)
+		}
+	}()
+
+	if err := buildProjectWorkbook(f, project, template); err != nil {
+		log.Printf("生成Excel文件失败: %v", err)
+		respondError(c, internalError("EXCEL_GENERATION_FAILED", err))
+		return
+	}
+
+	// 设置文件名
+	fileName := project.Name + template.label("file_suffix") + ".xlsx"
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+
+	// 写入响应
+	if err := f.Write(c.Writer); err != nil {
+		log.Printf("写入Excel文件失败: %v", err)
+		respondError(c, internalError("EXCEL_GENERATION_FAILED", err))
+		return
+	}
+}
+
+// 按导出模板生成工作簿
+type workbookBuilder struct {
+	f           *excelize.File
+	project     Project
+	tpl         *ExportTemplate
+	styles      *exportStyles
+	headerStyle int
+
+	// 阶段和任务的成本汇总，成本列使用
+	stageCosts map[uint]costSummary
+	taskCosts  map[uint]costSummary
+}
+
+// 阶段行Task为nil，任务行同时带有所属阶段
+type exportRow struct {
+	Stage *Stage
+	Task  *Task
+}
+
+// 各列的默认列宽
+var exportColumnWidths = map[string]float64{
+	"project":       20,
+	"stage":         25,
+	"task":          25,
+	"name":          25,
+	"start_date":    12,
+	"end_date":      12,
+	"duration":      15,
+	"status":        12,
+	"progress":      12,
+	"assignee":      15,
+	"priority":      12,
+	"planned_cost":  14,
+	"actual_cost":   14,
+	"cost_variance": 14,
+	"task_count":    10,
+	"overdue":       12,
+}
+
+// 创建工作簿生成器，预先生成共用的单元格样式
+func newWorkbookBuilder(f *excelize.File, tpl *ExportTemplate) (*workbookBuilder, error) {
+	styles, err := newExportStyles(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// 设置表头样式
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Bold:  true,
+			Color: tpl.HeaderFontColor,
+		},
+		Fill: excelize.Fill{
+			Type:    "pattern",
+			Color:   []string{tpl.HeaderColor},
+			Pattern: 1,
+		},
+		Alignment: &excelize.Alignment{
+			Horizontal: "center",
+			Vertical:   "center",
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &workbookBuilder{f: f, tpl: tpl, styles: styles, headerStyle: headerStyle}, nil
+}
+
+// 生成项目工作簿，工作表及顺序由模板决定
+func buildProjectWorkbook(f *excelize.File, project Project, tpl *ExportTemplate) error {
+	b, err := newWorkbookBuilder(f, tpl)
+	if err != nil {
+		return err
+	}
+	b.project = project
+
+	costs, err := computeProjectCosts(project)
+	if err != nil {
+		return err
+	}
+	b.stageCosts, b.taskCosts = map[uint]costSummary{}, map[uint]costSummary{}
+	for _, stage := range costs.Stages {
+		b.stageCosts[stage.StageID] = stage.costSummary
+		for _, task := range stage.Tasks {
+			b.taskCosts[task.TaskID] = task.costSummary
+		}
+	}
+
+	writers := map[string]func(sheetName string) error{
+		"overview": b.writeOverviewSheet,
+		"data":     b.writeDataSheet,
+		"timeline": b.writeTimelineSheet,
+		"members":  b.writeMembersSheet,
+		"hours":    b.writeHoursSheet,
+		"comments": b.writeCommentsSheet,
+		"evm":      b.writeEVMSheet,
+	}
+
+	for i, sheet := range tpl.Sheets {
+		sheetName := tpl.sheetName(sheet)
+		var err error
+		if i == 0 {
+			err = f.SetSheetName("Sheet1", sheetName)
+		} else {
+			_, err = f.NewSheet(sheetName)
+		}
+		if err != nil {
+			return err
+		}
+		if err := writers[sheet](sheetName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 按阶段和任务的顺序列出所有行
+func (b *workbookBuilder) rows() []exportRow {
+	var rows []exportRow
+	for i := range b.project.Stages {
+		stage := &b.project.Stages[i]
+		rows = append(rows, exportRow{Stage: stage})
+		for j := range stage.Tasks {
+			rows = append(rows, exportRow{Stage: stage, Task: &stage.Tasks[j]})
+		}
+	}
+	return rows
+}
+
+// 写入表头并设置列宽
+func (b *workbookBuilder) writeHeaders(sheetName string, firstCol, row int, columns []string) {
+	for i, column := range columns {
+		colLetter := getColumnLetter(firstCol + i)
+		b.f.SetCellValue(sheetName, colLetter+strconv.Itoa(row), b.tpl.header(column))
+		b.f.SetColWidth(sheetName, colLetter, colLetter, exportColumnWidths[column])
+	}
+	if len(columns) > 0 {
+		r := strconv.Itoa(row)
+		b.f.SetCellStyle(sheetName, getColumnLetter(firstCol)+r, getColumnLetter(firstCol+len(columns)-1)+r, b.headerStyle)
+	}
+}
+
+// 按模板配置的列写入一行阶段或任务数据
+func (b *workbookBuilder) writeRow(sheetName string, firstCol, row int, columns []string, item exportRow) {
+	r := strconv.Itoa(row)
+	cells := make(map[string]string, len(columns))
+	for i, column := range columns {
+		cells[column] = getColumnLetter(firstCol+i) + r
+	}
+
+	name, startDate, endDate := item.Stage.Name, item.Stage.StartDate, item.Stage.EndDate
+	status, progress := item.Stage.Status, item.Stage.Progress
+	cost := b.stageCosts[item.Stage.ID]
+	if item.Task != nil {
+		name, startDate, endDate = item.Task.Name, item.Task.StartDate, item.Task.EndDate
+		status, progress = item.Task.Status, item.Task.Progress
+		cost = b.taskCosts[item.Task.ID]
+	}
+
+	for _, column := range columns {
+		cell := cells[column]
+		switch column {
+		case "project":
+			b.f.SetCellValue(sheetName, cell, b.project.Name)
+		case "stage":
+			b.f.SetCellValue(sheetName, cell, item.Stage.Name)
+		case "task":
+			if item.Task != nil {
+				b.f.SetCellValue(sheetName, cell, item.Task.Name)
+			}
+		case "name":
+			if item.Task != nil {
+				name = "  " + name // 缩进表示任务
+			}
+			b.f.SetCellValue(sheetName, cell, name)
+		case "start_date":
+			setDateCell(b.f, sheetName, cell, startDate, b.styles)
+		case "end_date":
+			setDateCell(b.f, sheetName, cell, endDate, b.styles)
+		case "duration":
+			startCell, hasStart := cells["start_date"]
+			endCell, hasEnd := cells["end_date"]
+			if hasStart && hasEnd {
+				setDurationFormula(b.f, sheetName, cell, startCell, endCell, b.styles)
+			} else {
+				b.f.SetCellValue(sheetName, cell, calculateWorkDays(startDate, endDate))
+			}
+		case "status":
+			b.f.SetCellValue(sheetName, cell, b.tpl.statusText(status))
+		case "progress":
+			setProgressCell(b.f, sheetName, cell, progress, b.styles)
+		case "assignee":
+			if item.Task != nil && item.Task.Assignee.ID > 0 {
+				b.f.SetCellValue(sheetName, cell, item.Task.Assignee.Name)
+			}
+		case "priority":
+			if item.Task != nil {
+				b.f.SetCellValue(sheetName, cell, b.tpl.priorityText(item.Task.Priority))
+			}
+		case "planned_cost":
+			b.f.SetCellValue(sheetName, cell, cost.PlannedCost)
+			b.f.SetCellStyle(sheetName, cell, cell, b.styles.Cost)
+		case "actual_cost":
+			b.f.SetCellValue(sheetName, cell, cost.ActualCost)
+			style := b.styles.Cost
+			if cost.OverBudget {
+				style = b.styles.OverCost
+			}
+			b.f.SetCellStyle(sheetName, cell, cell, style)
+		case "cost_variance":
+			b.f.SetCellValue(sheetName, cell, cost.Variance)
+			b.f.SetCellStyle(sheetName, cell, cell, b.styles.Cost)
+		}
+	}
+}
+
+// 概览表：项目信息加阶段/任务列表，列表从C列开始
+func (b *workbookBuilder) writeOverviewSheet(sheetName string) error {
+	f, project, tpl := b.f, b.project, b.tpl
+
+	// 设置标题行
+	f.SetCellValue(sheetName, "A1", tpl.title())
+	f.SetCellValue(sheetName, "A2", tpl.label("info.name"))
+	f.SetCellValue(sheetName, "B2", project.Name)
+	f.SetCellValue(sheetName, "A3", tpl.label("info.description"))
+	f.SetCellValue(sheetName, "B3", project.Description)
+	f.SetCellValue(sheetName, "A4", tpl.label("info.start_date"))
+	setDateCell(f, sheetName, "B4", project.StartDate, b.styles)
+	f.SetCellValue(sheetName, "A5", tpl.label("info.end_date"))
+	setDateCell(f, sheetName, "B5", project.EndDate, b.styles)
+	f.SetCellValue(sheetName, "A6", tpl.label("info.status"))
+	f.SetCellValue(sheetName, "B6", tpl.statusText(project.Status))
+
+	// 设置列宽
+	f.SetColWidth(sheetName, "A", "A", 15)
+	f.SetColWidth(sheetName, "B", "B", 20)
+
+	// 设置表头
+	const firstCol = 3 // 从C列开始
+	b.writeHeaders(sheetName, firstCol, 8, tpl.OverviewColumns)
+
+	// 填充阶段和任务数据
+	row := 9
+	for _, item := range b.rows() {
+		b.writeRow(sheetName, firstCol, row, tpl.OverviewColumns, item)
+		row++
+	}
+
+	// 设置标题样式
+	titleStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Bold:  true,
+			Size:  16,
+			Color: "000000",
+		},
+		Alignment: &excelize.Alignment{
+			Horizontal: "center",
+			Vertical:   "center",
+		},
+	})
+	if err != nil {
+		return err
+	}
+	f.SetCellStyle(sheetName, "A1", "A1", titleStyle)
+	lastCol := firstCol + len(tpl.OverviewColumns) - 1
+	if lastCol < 2 {
+		lastCol = 2
+	}
+	return f.MergeCell(sheetName, "A1", getColumnLetter(lastCol)+"1")
+}
+
+// 数据表：每行一个阶段或任务，便于筛选和透视
+func (b *workbookBuilder) writeDataSheet(sheetName string) error {
+	b.writeHeaders(sheetName, 1, 1, b.tpl.DataColumns)
+
+	row := 2
+	for _, item := range b.rows() {
+		b.writeRow(sheetName, 1, row, b.tpl.DataColumns, item)
+		row++
+	}
+	return nil
+}
+
+// 时间线表中的一行
+type timelineItem struct {
+	Label     string
+	StartDate time.Time
+	EndDate   time.Time
+	Status    string
+}
+
+// 生成项目的甘特图时间线表
+func (b *workbookBuilder) writeTimelineSheet(sheetName string) error {
+	var items []timelineItem
+	for _, item := range b.rows() {
+		if item.Task == nil {
+			items = append(items, timelineItem{"📁 " + item.Stage.Name, item.Stage.StartDate, item.Stage.EndDate, item.Stage.Status})
+		} else {
+			items = append(items, timelineItem{"  📄 " + item.Task.Name, item.Task.StartDate, item.Task.EndDate, item.Task.Status})
+		}
+	}
+	return b.writeTimeline(sheetName, items, b.project.StartDate, b.project.EndDate)
+}
+
+// 写入时间线表
+// 每行保留开始/结束日期和状态，甘特图条由条件格式根据这些单元格绘制，
+// 在Excel中修改日期或状态后甘特图条会随之移动
+func (b *workbookBuilder) writeTimeline(sheetName string, items []timelineItem, startDate, endDate time.Time) error {
+	f, tpl := b.f, b.tpl
+
+	timelineHeaders := []string{tpl.label("timeline.name"), tpl.header("start_date"), tpl.header("end_date"), tpl.header("duration"), tpl.header("status")}
+	for i, header := range timelineHeaders {
+		f.SetCellValue(sheetName, getColumnLetter(i+1)+"1", header)
+	}
+	f.SetCellStyle(sheetName, "A1", getColumnLetter(timelineFixedCols)+"1", b.headerStyle)
+
+	// 生成日期标题行
+	current := startDate
+	col := timelineFixedCols + 1
+	for !startDate.IsZero() && (current.Before(endDate) || current.Equal(endDate)) {
+		colLetter := getColumnLetter(col)
+		setDateCell(f, sheetName, colLetter+"1", current, b.styles)
+		f.SetCellStyle(sheetName, colLetter+"1", colLetter+"1", b.styles.Day)
+		current = current.AddDate(0, 0, 1)
+		col++
+	}
+	lastDateCol := col - 1
+
+	// 填充甘特图数据
+	row := 2
+	for _, item := range items {
+		r := strconv.Itoa(row)
+		f.SetCellValue(sheetName, "A"+r, item.Label)
+		setScheduleCells(f, sheetName, "B", row, item.StartDate, item.EndDate, b.styles)
+		f.SetCellValue(sheetName, "E"+r, tpl.statusText(item.Status))
+		row++
+	}
+	lastRow := row - 1
+
+	// 设置甘特图时间线表的列宽
+	f.SetColWidth(sheetName, "A", "A", 30)
+	f.SetColWidth(sheetName, "B", "C", 12)
+	f.SetColWidth(sheetName, "D", "D", 12)
+	f.SetColWidth(sheetName, "E", "E", 10)
+	if lastDateCol <= timelineFixedCols {
+		return nil
+	}
+	firstDateLetter := getColumnLetter(timelineFixedCols + 1)
+	lastDateLetter := getColumnLetter(lastDateCol)
+	f.SetColWidth(sheetName, firstDateLetter, lastDateLetter, 3)
+
+	// 标记周末
+	weekendStyle, err := f.NewConditionalStyle(&excelize.Style{
+		Fill: excelize.Fill{
+			Type:    "pattern",
+			Color:   []string{"FFE6E6"},
+			Pattern: 1,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if err := f.SetConditionalFormat(sheetName, firstDateLetter+"1:"+lastDateLetter+"1", []excelize.ConditionalFormatOptions{
+		{Type: "formula", Criteria: fmt.Sprintf("WEEKDAY(%s1,2)>5", firstDateLetter), Format: weekendStyle},
+	}); err != nil {
+		return err
+	}
+
+	if lastRow < 2 {
+		return nil
+	}
+	return b.addGanttBarRules(sheetName, firstDateLetter, lastDateLetter, lastRow)
+}
+
+// 为时间线区域添加绘制甘特图条的条件格式，颜色按状态区分
+func (b *workbookBuilder) addGanttBarRules(sheetName, firstDateLetter, lastDateLetter string, lastRow int) error {
+	// 当前单元格的日期落在该行开始和结束日期之间
+	inRange := fmt.Sprintf("AND(%[1]s$1>=$B2,%[1]s$1<=$C2)", firstDateLetter)
+
+	var rules []excelize.ConditionalFormatOptions
+	for _, status := range []string{"pending", "in_progress", "completed", "active", "paused"} {
+		style, err := newGanttBarStyle(b.f, b.tpl.statusColor(status))
+		if err != nil {
+			return err
+		}
+		rules = append(rules, excelize.ConditionalFormatOptions{
+			Type:       "formula",
+			Criteria:   fmt.Sprintf(`AND(%s,$E2="%s")`, inRange, b.tpl.statusText(status)),
+			Format:     style,
+			StopIfTrue: true,
+		})
+	}
+
+	// 未知状态使用默认颜色
+	defaultStyle, err := newGanttBarStyle(b.f, b.tpl.statusColor(""))
+	if err != nil {
+		return err
+	}
+	rules = append(rules, excelize.ConditionalFormatOptions{
+		Type:     "formula",
+		Criteria: inRange,
+		Format:   defaultStyle,
+	})
+
+	rangeRef := fmt.Sprintf("%s2:%s%d", firstDateLetter, lastDateLetter, lastRow)
+	return b.f.SetConditionalFormat(sheetName, rangeRef, rules)
+}
+
+// 团队成员信息表
+func (b *workbookBuilder) writeMembersSheet(sheetName string) error {
+	f, tpl := b.f, b.tpl
+
+	// 团队成员表头
+	memberHeaders := []string{"name", "role", "email", "avatar", "joined", "status"}
+	for i, header := range memberHeaders {
+		col := string(rune('A' + i))
+		f.SetCellValue(sheetName, col+"1", tpl.label("member."+header))
+	}
+
+	// 填充团队成员数据
+	row := 2
+	for _, member := range b.project.TeamMembers {
+		r := strconv.Itoa(row)
+		f.SetCellValue(sheetName, "A"+r, member.Name)
+		f.SetCellValue(sheetName, "B"+r, member.Role)
+		f.SetCellValue(sheetName, "C"+r, member.Email)
+		if err := b.writeAvatarCell(sheetName, "D"+r, row, &member); err != nil {
+			return err
+		}
+		setDateCell(f, sheetName, "E"+r, member.CreatedAt, b.styles)
+		if member.IsActive {
+			f.SetCellValue(sheetName, "F"+r, tpl.label("member.active"))
+		} else {
+			f.SetCellValue(sheetName, "F"+r, tpl.label("member.inactive"))
+		}
+		row++
+	}
+
+	// 设置团队成员表的列宽
+	f.SetColWidth(sheetName, "A", "A", 15)
+	f.SetColWidth(sheetName, "B", "B", 15)
+	f.SetColWidth(sheetName, "C", "C", 25)
+	f.SetColWidth(sheetName, "D", "D", 20)
+	f.SetColWidth(sheetName, "E", "E", 12)
+	f.SetColWidth(sheetName, "F", "F", 10)
+
+	// 设置团队成员表头样式
+	return f.SetCellStyle(sheetName, "A1", "F1", b.headerStyle)
+}
+
+// 工时表：每位成员在各阶段记录的工时，最后两行为实际工时合计和任务预估工时合计
+func (b *workbookBuilder) writeHoursSheet(sheetName string) error {
+	f, tpl := b.f, b.tpl
+
+	var rows []struct {
+		MemberID uint
+		StageID  uint
+		Hours    float64
+	}
+	if err := DB.Model(&TimeEntry{}).Select("time_entries.member_id, tasks.stage_id, SUM(time_entries.hours) AS hours").
+		Joins("JOIN tasks ON tasks.id = time_entries.task_id").
+		Where("time_entries.project_id = ?", b.project.ID).
+		Group("time_entries.member_id, tasks.stage_id").Scan(&rows).Error; err != nil {
+		return err
+	}
+	hours := map[uint]map[uint]float64{}
+	for _, row := range rows {
+		if hours[row.MemberID] == nil {
+			hours[row.MemberID] = map[uint]float64{}
+		}
+		hours[row.MemberID][row.StageID] = row.Hours
+	}
+
+	stages := b.project.Stages
+	totalCol := getColumnLetter(len(stages) + 2)
+	f.SetCellValue(sheetName, "A1", tpl.label("hours.member"))
+	for i, stage := range stages {
+		f.SetCellValue(sheetName, getColumnLetter(i+2)+"1", stage.Name)
+	}
+	f.SetCellValue(sheetName, totalCol+"1", tpl.label("hours.total"))
+
+	row := 2
+	for _, member := range b.project.TeamMembers {
+		if hours[member.ID] == nil && !member.IsActive {
+			continue
+		}
+		r := strconv.Itoa(row)
+		f.SetCellValue(sheetName, "A"+r, member.Name)
+		for i, stage := range stages {
+			f.SetCellValue(sheetName, getColumnLetter(i+2)+r, hours[member.ID][stage.ID])
+		}
+		f.SetCellFormula(sheetName, totalCol+r, fmt.Sprintf("SUM(B%s:%s%s)", r, getColumnLetter(len(stages)+1), r))
+		row++
+	}
+	lastMemberRow := row - 1
+
+	// 合计行和预估工时行
+	actual, estimated := strconv.Itoa(row), strconv.Itoa(row+1)
+	f.SetCellValue(sheetName, "A"+actual, tpl.label("hours.actual_total"))
+	f.SetCellValue(sheetName, "A"+estimated, tpl.label("hours.estimated_total"))
+	for i, stage := range stages {
+		col := getColumnLetter(i + 2)
+		f.SetCellFormula(sheetName, col+actual, fmt.Sprintf("SUM(%s2:%s%d)", col, col, lastMemberRow))
+		var estimate float64
+		for _, task := range stage.Tasks {
+			estimate += task.EstimatedHours
+		}
+		f.SetCellValue(sheetName, col+estimated, estimate)
+	}
+	for _, r := range []string{actual, estimated} {
+		f.SetCellFormula(sheetName, totalCol+r, fmt.Sprintf("SUM(B%s:%s%s)", r, getColumnLetter(len(stages)+1), r))
+	}
+
+	if err := f.SetCellStyle(sheetName, "B2", totalCol+estimated, b.styles.Hours); err != nil {
+		return err
+	}
+	f.SetColWidth(sheetName, "A", "A", 15)
+	f.SetColWidth(sheetName, "B", totalCol, 14)
+	return f.SetCellStyle(sheetName, "A1", totalCol+"1", b.headerStyle)
+}
+
+// 评论表，按时间顺序列出所有任务和阶段的评论
+func (b *workbookBuilder) writeCommentsSheet(sheetName string) error {
+	f, tpl := b.f, b.tpl
+
+	var comments []Comment
+	if err := DB.Where("project_id = ?", b.project.ID).Preload("Author").Preload("Mentions").
+		Order("created_at").Order("id").Find(&comments).Error; err != nil {
+		return err
+	}
+
+	stageNames := map[uint]string{}
+	taskNames := map[uint]string{}
+	for _, stage := range b.project.Stages {
+		stageNames[stage.ID] = stage.Name
+		for _, task := range stage.Tasks {
+			taskNames[task.ID] = task.Name
+		}
+	}
+
+	commentHeaders := []string{"type", "target", "author", "body", "mentions", "time"}
+	for i, header := range commentHeaders {
+		col := string(rune('A' + i))
+		f.SetCellValue(sheetName, col+"1", tpl.label("comment."+header))
+	}
+
+	for i, comment := range comments {
+		r := strconv.Itoa(i + 2)
+		if comment.TaskID != nil {
+			f.SetCellValue(sheetName, "A"+r, tpl.label("col.task"))
+			f.SetCellValue(sheetName, "B"+r, taskNames[*comment.TaskID])
+		} else if comment.StageID != nil {
+			f.SetCellValue(sheetName, "A"+r, tpl.label("col.stage"))
+			f.SetCellValue(sheetName, "B"+r, stageNames[*comment.StageID])
+		}
+		f.SetCellValue(sheetName, "C"+r, comment.Author.Name)
+		f.SetCellValue(sheetName, "D"+r, comment.Body)
+		names := make([]string, len(comment.Mentions))
+		for j, member := range comment.Mentions {
+			names[j] = member.Name
+		}
+		f.SetCellValue(sheetName, "E"+r, strings.Join(names, ", "))
+		f.SetCellValue(sheetName, "F"+r, comment.CreatedAt.Format("2006-01-02 15:04"))
+	}
+
+	// 正文自动换行
+	wrapStyle, err := f.NewStyle(&excelize.Style{Alignment: &excelize.Alignment{WrapText: true, Vertical: "top"}})
+	if err != nil {
+		return err
+	}
+	if len(comments) > 0 {
+		if err := f.SetCellStyle(sheetName, "D2", "D"+strconv.Itoa(len(comments)+1), wrapStyle); err != nil {
+			return err
+		}
+	}
+
+	f.SetColWidth(sheetName, "A", "A", 8)
+	f.SetColWidth(sheetName, "B", "B", 25)
+	f.SetColWidth(sheetName, "C", "C", 12)
+	f.SetColWidth(sheetName, "D", "D", 60)
+	f.SetColWidth(sheetName, "E", "E", 20)
+	f.SetColWidth(sheetName, "F", "F", 17)
+
+	return f.SetCellStyle(sheetName, "A1", "F1", b.headerStyle)
+}
+
+// 上传过头像的成员嵌入头像缩略图，否则写入头像地址
+func (b *workbookBuilder) writeAvatarCell(sheetName, cell string, row int, member *TeamMember) error {
+	if member.AvatarKey == "" {
+		return b.f.SetCellValue(sheetName, cell, member.Avatar)
+	}
+
+	thumbnail, err := avatarThumbnail(context.Background(), member.AvatarKey)
+	if err != nil {
+		log.Printf("读取成员 %d 的头像失败: %v", member.ID, err)
+		return b.f.SetCellValue(sheetName, cell, member.Avatar)
+	}
+
+	// 行高以磅为单位，像素约为磅的 4/3
+	if err := b.f.SetRowHeight(sheetName, row, float64(avatarThumbSize)*0.75+6); err != nil {
+		return err
+	}
+	return b.f.AddPictureFromBytes(sheetName, cell, &excelize.Picture{
+		Extension: ".png",
+		File:      thumbnail,
+		Format:    &excelize.GraphicOptions{AltText: member.Name, OffsetX: 4, OffsetY: 4, Positioning: "oneCell"},
+	})
+}
+
+func newGanttBarStyle(f *excelize.File, color string) (int, error) {
+	return f.NewConditionalStyle(&excelize.Style{
+		Fill: excelize.Fill{
+			Type:    "pattern",
+			Color:   []string{color},
+			Pattern: 1,
+		},
+		Border: []excelize.Border{
+			{Type: "top", Color: "000000", Style: 1},
+			{Type: "bottom", Color: "000000", Style: 1},
+		},
+	})
+}
+
+// 写入开始日期、结束日期以及按NETWORKDAYS计算的工期公式，三列相邻
+func setScheduleCells(f *excelize.File, sheetName, startCol string, row int, startDate, endDate time.Time, styles *exportStyles) {
+	colNum, _ := excelize.ColumnNameToNumber(startCol)
+	r := strconv.Itoa(row)
+	startCell := startCol + r
+	endCell := getColumnLetter(colNum+1) + r
+	durationCell := getColumnLetter(colNum+2) + r
+
+	setDateCell(f, sheetName, startCell, startDate, styles)
+	setDateCell(f, sheetName, endCell, endDate, styles)
+	setDurationFormula(f, sheetName, durationCell, startCell, endCell, styles)
+}
+
+// 写入按NETWORKDAYS计算工期的公式，日期为空时工期为0
+func setDurationFormula(f *excelize.File, sheetName, cell, startCell, endCell string, styles *exportStyles) {
+	f.SetCellFormula(sheetName, cell, fmt.Sprintf(`IF(OR(%[1]s="",%[2]s=""),0,NETWORKDAYS(%[1]s,%[2]s))`, startCell, endCell))
+	f.SetCellStyle(sheetName, cell, cell, styles.Duration)
+}
+
+// 写入日期单元格，零值日期留空
+func setDateCell(f *excelize.File, sheetName, cell string, date time.Time, styles *exportStyles) {
+	if date.IsZero() {
+		return
+	}
+	f.SetCellValue(sheetName, cell, excelDate(date))
+	f.SetCellStyle(sheetName, cell, cell, styles.Date)
+}
+
+// 进度以0-1的小数写入并使用百分比格式
+func setProgressCell(f *excelize.File, sheetName, cell string, progress float64, styles *exportStyles) {
+	f.SetCellFloat(sheetName, cell, progress/100, 4, 64)
+	f.SetCellStyle(sheetName, cell, cell, styles.Percent)
+}
+
+// Excel日期不带时区，按日期本身的年月日转换为UTC零点，避免时区导致日期偏移
+func excelDate(t time.Time) time.Time {
+	return dateOnly(t)
+}
+
+// 获取列字母 (1=A, 2=B, 27=AA, etc.)
+func getColumnLetter(col int) string {
+	result := ""
+	for col > 0 {
+		col--
+		result = string(rune('A'+col%26)) + result
+		col /= 26
+	}
+	return result
+}
+
+// 获取状态颜色
+func getStatusColor(status string) string {
+	statusColorMap := map[string]string{
+		"pending":     "FFE6B3", // 橙色
+		"in_progress": "FFB366", // 深橙色
+		"completed":   "90EE90", // 浅绿色
+		"active":      "87CEEB", // 天蓝色
+		"paused":      "DDA0DD", // 紫色
+	}
+	if color, exists := statusColorMap[status]; exists {
+		return color
+	}
+	return "E0E0E0" // 默认灰色
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 导出模板可选的工作表，comments 需在模板中显式选择
+var (
+	exportSheetKeys     = []string{"overview", "data", "timeline", "members", "hours", "comments", "evm"}
+	defaultExportSheets = []string{"overview", "data", "timeline", "members", "hours", "evm"}
+)
+
+// 导出模板可选的列
+var exportColumnKeys = []string{"project", "stage", "task", "name", "start_date", "end_date", "duration", "status", "progress", "assignee", "priority", "planned_cost", "actual_cost", "cost_variance"}
+
+var (
+	defaultOverviewColumns = []string{"name", "start_date", "end_date", "duration", "status", "progress", "assignee", "priority"}
+	defaultDataColumns     = []string{"project", "stage", "task", "start_date", "end_date", "duration", "status", "progress", "assignee", "priority", "planned_cost", "actual_cost", "cost_variance"}
+)
+
+// 预定义导出模板
+func GetDefaultExportTemplates() []ExportTemplate {
+	return []ExportTemplate{
+		{Name: "default", Description: "默认模板，语言跟随请求"},
+		{Name: "english", Description: "English template for external clients", Locale: "en-US"},
+	}
+}
+
+func initDefaultExportTemplates() {
+	var count int64
+	DB.Model(&ExportTemplate{}).Count(&count)
+
+	if count == 0 {
+		templates := GetDefaultExportTemplates()
+		for _, template := range templates {
+			DB.Create(&template)
+		}
+		log.Println("Default export templates initialized")
+	}
+}
+
+// 根据名称或ID查找导出模板，名称为空时使用默认模板
+// 模板未指定语言时使用请求语言
+func findExportTemplate(nameOrID, locale string) (*ExportTemplate, error) {
+	var template ExportTemplate
+	if nameOrID == "" {
+		nameOrID = "default"
+	}
+
+	err := DB.Where("name = ?", nameOrID).First(&template).Error
+	if err == gorm.ErrRecordNotFound && nameOrID == "default" {
+		// 数据库中没有默认模板时使用内置配置
+		template = GetDefaultExportTemplates()[0]
+		return template.withDefaults(locale), nil
+	}
+	if err == gorm.ErrRecordNotFound && isNumeric(nameOrID) {
+		err = DB.First(&template, nameOrID).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return template.withDefaults(locale), nil
+}
+
+// 补全模板中未配置的项
+func (t ExportTemplate) withDefaults(locale string) *ExportTemplate {
+	if !isSupportedLocale(t.Locale) {
+		t.Locale = locale
+	}
+	if !isSupportedLocale(t.Locale) {
+		t.Locale = defaultLocale
+	}
+	if len(t.Sheets) == 0 {
+		t.Sheets = defaultExportSheets
+	}
+	if len(t.OverviewColumns) == 0 {
+		t.OverviewColumns = defaultOverviewColumns
+	}
+	if len(t.DataColumns) == 0 {
+		t.DataColumns = defaultDataColumns
+	}
+	if t.HeaderColor == "" {
+		t.HeaderColor = "366092"
+	}
+	if t.HeaderFontColor == "" {
+		t.HeaderFontColor = "FFFFFF"
+	}
+	t.HeaderColor = strings.TrimPrefix(t.HeaderColor, "#")
+	t.HeaderFontColor = strings.TrimPrefix(t.HeaderFontColor, "#")
+	return &t
+}
+
+// 获取模板语言下的文字
+func (t *ExportTemplate) label(key string) string {
+	return T(t.Locale, key)
+}
+
+// 获取表头文字，优先使用模板中的覆盖配置
+func (t *ExportTemplate) header(column string) string {
+	if text, exists := t.Headers[column]; exists && text != "" {
+		return text
+	}
+	return t.label("col." + column)
+}
+
+// 获取工作表名称
+func (t *ExportTemplate) sheetName(sheet string) string {
+	if name, exists := t.SheetNames[sheet]; exists && name != "" {
+		return name
+	}
+	return t.label("sheet." + sheet)
+}
+
+func (t *ExportTemplate) title() string {
+	if t.Title != "" {
+		return t.Title
+	}
+	return t.label("title")
+}
+
+func (t *ExportTemplate) hasSheet(sheet string) bool {
+	return containsString(t.Sheets, sheet)
+}
+
+func (t *ExportTemplate) statusText(status string) string {
+	return getStatusText(t.Locale, status)
+}
+
+func (t *ExportTemplate) priorityText(priority string) string {
+	return getPriorityText(t.Locale, priority)
+}
+
+// 获取状态颜色，优先使用模板中的调色板
+func (t *ExportTemplate) statusColor(status string) string {
+	if color, exists := t.StatusColors[status]; exists && color != "" {
+		return strings.TrimPrefix(color, "#")
+	}
+	return getStatusColor(status)
+}
+
+// 校验模板配置
+func validateExportTemplate(t *ExportTemplate) *APIError {
+	if t.Name == "" {
+		return newAPIError(http.StatusUnprocessableEntity, "TEMPLATE_NAME_REQUIRED")
+	}
+	if t.Locale != "" && !isSupportedLocale(t.Locale) {
+		return newAPIError(http.StatusUnprocessableEntity, "UNSUPPORTED_LOCALE", t.Locale)
+	}
+	for i, sheet := range t.Sheets {
+		if !containsString(exportSheetKeys, sheet) {
+			return newAPIError(http.StatusUnprocessableEntity, "UNKNOWN_SHEET", sheet)
+		}
+		if containsString(t.Sheets[:i], sheet) {
+			return newAPIError(http.StatusUnprocessableEntity, "DUPLICATE_SHEET", sheet)
+		}
+	}
+	for _, name := range t.SheetNames {
+		if name != "" && !validSheetName(name) {
+			return newAPIError(http.StatusUnprocessableEntity, "INVALID_SHEET_NAME", name)
+		}
+	}
+	if name := duplicateSheetName(t); name != "" {
+		return newAPIError(http.StatusUnprocessableEntity, "DUPLICATE_SHEET_NAME", name)
+	}
+	for _, columns := range [][]string{t.OverviewColumns, t.DataColumns} {
+		for _, column := range columns {
+			if !containsString(exportColumnKeys, column) {
+				return newAPIError(http.StatusUnprocessableEntity, "UNKNOWN_COLUMN", column)
+			}
+		}
+	}
+	return nil
+}
+
+// Excel工作表名称：1到31个字符，不含 []:*?/\，不以单引号开头或结尾
+func validSheetName(name string) bool {
+	return name != "" && utf8.RuneCountInString(name) <= maxSheetNameLength &&
+		!strings.ContainsAny(name, `[]:*?/\`) &&
+		!strings.HasPrefix(name, "'") && !strings.HasSuffix(name, "'")
+}
+
+// 同一工作簿中的工作表名称不能重复（不区分大小写）
+// 模板未指定语言时按每种支持的语言检查默认名称
+func duplicateSheetName(t *ExportTemplate) string {
+	locales := supportedLocales
+	if isSupportedLocale(t.Locale) {
+		locales = []string{t.Locale}
+	}
+	sheets := t.Sheets
+	if len(sheets) == 0 {
+		sheets = defaultExportSheets
+	}
+
+	for _, locale := range locales {
+		tpl := *t
+		tpl.Locale = locale
+		for _, group := range [][]string{sheets, {"portfolio", "combined"}} {
+			used := map[string]bool{}
+			for _, sheet := range group {
+				name := strings.ToLower(tpl.sheetName(sheet))
+				if used[name] {
+					return tpl.sheetName(sheet)
+				}
+				used[name] = true
+			}
+		}
+	}
+	return ""
+}
+
+// 导出模板相关接口
+func getExportTemplates(c *gin.Context) {
+	var templates []ExportTemplate
+	if err := DB.Order("id").Find(&templates).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+func createExportTemplate(c *gin.Context) {
+	var template ExportTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	if verr := validateExportTemplate(&template); verr != nil {
+		respondError(c, verr)
+		return
+	}
+
+	template.ID = 0
+	template.CreatedAt = time.Now()
+	template.UpdatedAt = time.Now()
+
+	if err := DB.Create(&template).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+func updateExportTemplate(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	var template ExportTemplate
+
+	if err := DB.First(&template, id).Error; err != nil {
+		respondError(c, dbError(err, "EXPORT_TEMPLATE_NOT_FOUND"))
+		return
+	}
+
+	// 整体替换模板配置，保留ID和创建时间
+	var input ExportTemplate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+	input.ID = template.ID
+	input.CreatedAt = template.CreatedAt
+
+	if verr := validateExportTemplate(&input); verr != nil {
+		respondError(c, verr)
+		return
+	}
+
+	input.UpdatedAt = time.Now()
+
+	if err := DB.Save(&input).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, input)
+}
+
+func deleteExportTemplate(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	result := DB.Delete(&ExportTemplate{}, id)
+	if result.Error != nil {
+		respondError(c, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondError(c, notFound("EXPORT_TEMPLATE_NOT_FOUND"))
+		return
+	}
+
+	respondMessage(c, http.StatusOK, "EXPORT_TEMPLATE_DELETED")
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateExportTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template ExportTemplate
+		code     string
+	}{
+		{"默认配置", ExportTemplate{Name: "acme"}, ""},
+		{"覆盖工作表名称", ExportTemplate{Name: "acme", Locale: "en-US", Sheets: []string{"overview", "data"},
+			SheetNames: map[string]string{"overview": "ACME Plan", "data": "ACME 'Data' v2"}}, ""},
+		{"缺少名称", ExportTemplate{}, "TEMPLATE_NAME_REQUIRED"},
+		{"未知的工作表", ExportTemplate{Name: "acme", Sheets: []string{"gantt"}}, "UNKNOWN_SHEET"},
+		{"重复的工作表", ExportTemplate{Name: "acme", Sheets: []string{"overview", "data", "overview"}}, "DUPLICATE_SHEET"},
+		{"名称过长", ExportTemplate{Name: "acme", SheetNames: map[string]string{"overview": strings.Repeat("甘", 32)}}, "INVALID_SHEET_NAME"},
+		{"名称含非法字符", ExportTemplate{Name: "acme", SheetNames: map[string]string{"data": "Q1/Q2"}}, "INVALID_SHEET_NAME"},
+		{"名称以单引号开头", ExportTemplate{Name: "acme", SheetNames: map[string]string{"data": "'Data"}}, "INVALID_SHEET_NAME"},
+		{"与其他工作表的名称相同", ExportTemplate{Name: "acme", Locale: "en-US",
+			SheetNames: map[string]string{"data": "gantt"}}, "DUPLICATE_SHEET_NAME"},
+		{"未指定语言时与中文默认名称相同", ExportTemplate{Name: "acme",
+			SheetNames: map[string]string{"data": "甘特图"}}, "DUPLICATE_SHEET_NAME"},
+		{"未选择的工作表不算重名", ExportTemplate{Name: "acme", Locale: "en-US", Sheets: []string{"overview", "hours"},
+			SheetNames: map[string]string{"hours": "Gantt Data"}}, ""},
+		{"项目汇总与组合时间线重名", ExportTemplate{Name: "acme",
+			SheetNames: map[string]string{"portfolio": "Plan", "combined": "PLAN"}}, "DUPLICATE_SHEET_NAME"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := ""
+			if err := validateExportTemplate(&tt.template); err != nil {
+				code = err.Code
+			}
+			if code != tt.code {
+				t.Errorf("validateExportTemplate() = %q, want %q", code, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,558 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 支持的语言，第一个为默认语言
+var supportedLocales = []string{"zh-CN", "en-US"}
+
+const defaultLocale = "zh-CN"
+
+// 上下文中保存请求语言的键
+const localeContextKey = "locale"
+
+// 各语言的文字，错误码使用大写键，导出文字使用小写带点的键
+var translations = map[string]map[string]string{
+	"zh-CN": {
+		// 错误信息
+		"INVALID_REQUEST_BODY":          "请求数据格式错误",
+		"INVALID_PARAMETER":             "请求参数错误",
+		"SEARCH_QUERY_REQUIRED":         "请输入搜索关键词",
+		"SEARCH_MEMBER_REQUIRED":        "请通过 member 参数提供成员邮箱，只搜索该成员所在的项目",
+		"VIEW_NOT_FOUND":                "视图不存在",
+		"VIEW_NAME_TAKEN":               "已有同名视图",
+		"VIEW_DELETED":                  "视图删除成功",
+		"VALIDATION_FAILED":             "数据校验失败",
+		"INVALID_FIELD":                 "字段值无效",
+		"INVALID_TYPE":                  "字段类型错误，应为 %s",
+		"DATABASE_ERROR":                "数据库操作失败",
+		"RESOURCE_NOT_FOUND":            "资源不存在",
+		"DUPLICATE_RESOURCE":            "资源已存在",
+		"RESOURCE_IN_USE":               "资源仍被其他数据引用",
+		"REFERENCED_RESOURCE_NOT_FOUND": "引用的资源不存在",
+		"CONSTRAINT_VIOLATION":          "数据不满足约束条件",
+		"FIELD_REQUIRED":                "不能为空",
+		"INVALID_ENUM":                  "取值无效，可选值: %s",
+		"OUT_OF_RANGE":                  "取值必须在 %v 到 %v 之间",
+		"INVALID_DATE":                  "日期格式无效",
+		"END_BEFORE_START":              "结束日期不能早于开始日期",
+		"OUTSIDE_PROJECT_RANGE":         "日期必须在项目日期范围内（%s 至 %s）",
+		"OUTSIDE_STAGE_RANGE":           "日期必须在阶段日期范围内（%s 至 %s）",
+		"STAGES_OUTSIDE_RANGE":          "已有阶段超出该日期范围（%s）",
+		"TASKS_OUTSIDE_RANGE":           "已有任务超出该日期范围（%s）",
+		"REFERENCE_NOT_FOUND":           "引用的记录不存在: %v",
+		"ASSIGNEE_NOT_IN_PROJECT":       "负责人不是该项目的成员",
+		"INVALID_EMAIL":                 "邮箱格式无效",
+		"INVALID_URL":                   "URL无效，必须是 http 或 https 地址",
+		"UNKNOWN_EVENT":                 "未知的事件类型: %s",
+		"DUPLICATE_ID":                  "ID重复: %v",
+		"MISSING_ID":                    "缺少ID: %v",
+		"FIELD_NOT_PATCHABLE":           "该字段不可修改",
+		"PATCH_NOT_OBJECT":              "补丁必须是JSON对象",
+		"IF_MATCH_REQUIRED":             "缺少 If-Match 请求头",
+		"VERSION_CONFLICT":              "数据已被其他人修改，请刷新后重试",
+		"VERSION_REQUIRED":              "按ID操作时必须提供版本号",
+		"INVALID_BULK_SIZE":             "操作数量必须在 1 到 %d 之间",
+		"INVALID_BULK_OP":               "不支持的批量操作: %s",
+		"BULK_TARGET_REQUIRED":          "缺少操作对象ID",
+		"BULK_OPERATION_FAILED":         "第 %d 个操作失败，所有操作已回滚",
+		"PROJECT_NOT_FOUND":             "项目不存在",
+		"STAGE_NOT_FOUND":               "阶段不存在",
+		"TASK_NOT_FOUND":                "任务不存在",
+		"MEMBER_NOT_FOUND":              "团队成员不存在",
+		"EXPORT_TEMPLATE_NOT_FOUND":     "导出模板不存在",
+		"WEBHOOK_NOT_FOUND":             "Webhook不存在",
+		"WEBHOOK_DELIVERY_NOT_FOUND":    "投递记录不存在",
+		"WEBHOOK_SECRET_FAILED":         "生成Webhook密钥失败",
+		"INVALID_DATE_RANGE":            "开始日期不能晚于结束日期",
+		"INVALID_START_DATE":            "无效的开始日期",
+		"INVALID_END_DATE":              "无效的结束日期",
+		"INVALID_ID":                    "无效的ID参数: %s",
+		"DELETE_TASKS_FAILED":           "删除相关任务失败",
+		"DELETE_STAGES_FAILED":          "删除相关阶段失败",
+		"DELETE_MEMBERS_FAILED":         "删除相关团队成员失败",
+		"DELETE_PROJECT_FAILED":         "删除项目失败",
+		"DELETE_FAILED":                 "删除操作失败",
+		"PROJECT_LOAD_FAILED":           "获取项目信息失败",
+		"EXPORT_TEMPLATE_LOAD_FAILED":   "获取导出模板失败",
+		"EXCEL_GENERATION_FAILED":       "生成Excel文件失败",
+		"INVALID_LAYOUT":                "无效的布局参数",
+		"TEMPLATE_NAME_REQUIRED":        "模板名称不能为空",
+		"UNSUPPORTED_LOCALE":            "不支持的语言: %s",
+		"UNKNOWN_SHEET":                 "未知的工作表: %s",
+		"DUPLICATE_SHEET":               "重复的工作表: %s",
+		"INVALID_SHEET_NAME":            "无效的工作表名称: %s",
+		"DUPLICATE_SHEET_NAME":          "工作表名称重复: %s",
+		"UNKNOWN_COLUMN":                "未知的列: %s",
+
+		// 提示信息
+		"SERVICE_OK":              "咸鱼甘特图后端服务运行正常",
+		"PROJECT_DELETED":         "项目删除成功",
+		"EXPORT_TEMPLATE_DELETED": "导出模板删除成功",
+		"WEBHOOK_DELETED":         "Webhook删除成功",
+
+		// 导出文字
+		"title":              "项目甘特图",
+		"file_suffix":        "_甘特图",
+		"sheet.overview":     "甘特图",
+		"sheet.data":         "甘特图数据",
+		"sheet.timeline":     "甘特图时间线",
+		"sheet.members":      "团队成员信息",
+		"info.name":          "项目名称",
+		"info.description":   "项目描述",
+		"info.start_date":    "开始日期",
+		"info.end_date":      "结束日期",
+		"info.status":        "状态",
+		"col.project":        "项目",
+		"col.stage":          "阶段",
+		"col.task":           "任务",
+		"col.name":           "阶段/任务",
+		"col.start_date":     "开始日期",
+		"col.end_date":       "结束日期",
+		"col.duration":       "工期(工作日)",
+		"col.status":         "状态",
+		"col.progress":       "进度",
+		"col.assignee":       "负责人",
+		"col.priority":       "优先级",
+		"timeline.name":      "任务/阶段",
+		"member.name":        "姓名",
+		"member.role":        "角色",
+		"member.email":       "邮箱",
+		"member.avatar":      "头像",
+		"member.joined":      "加入时间",
+		"member.status":      "状态",
+		"member.active":      "活跃",
+		"member.inactive":    "非活跃",
+		"status.pending":     "待开始",
+		"status.in_progress": "进行中",
+		"status.completed":   "已完成",
+		"status.active":      "活跃",
+		"status.paused":      "暂停",
+		"priority.low":       "低",
+		"priority.medium":    "中",
+		"priority.high":      "高",
+		"priority.urgent":    "紧急",
+		"portfolio.file":     "项目组合报告",
+		"sheet.portfolio":    "项目汇总",
+		"sheet.combined":     "组合时间线",
+		"col.task_count":     "任务数",
+		"col.overdue":        "逾期任务数",
+
+		// 通知和邮件
+		"NOTIFICATION_NOT_FOUND":    "通知不存在",
+		"notify.task_overdue":       "任务「%s」已于 %s 到期，尚未完成",
+		"notify.task_due_soon":      "任务「%s」将于 %s 到期",
+		"notify.stage_overdue":      "阶段「%s」已于 %s 到期，尚未完成",
+		"notify.stage_due_soon":     "阶段「%s」将于 %s 到期",
+		"notify.task_assigned":      "任务「%s」已分配给您，截止日期 %s",
+		"notify.task_unassigned":    "任务「%[1]s」已改由他人负责",
+		"notify.task_rescheduled":   "任务「%s」的日期已调整，新的截止日期为 %s",
+		"notify.task_completed":     "任务「%[1]s」已完成",
+		"mail.notification_subject": "[甘特图] 您有 %d 条新通知",
+		"mail.digest_subject":       "[甘特图] %s 通知汇总",
+
+		// 评论
+		"COMMENT_NOT_FOUND":       "评论不存在",
+		"COMMENT_DELETED":         "评论删除成功",
+		"DELETE_COMMENTS_FAILED":  "删除评论失败",
+		"COMMENT_TARGET_REQUIRED": "需要且只能指定 task_id 或 stage_id 之一",
+		"AUTHOR_NOT_IN_PROJECT":   "作者不是该项目的成员",
+		"notify.comment_mention":  "有人在「%[1]s」的评论中提到了您",
+		"sheet.comments":          "评论",
+		"comment.type":            "类型",
+		"comment.target":          "名称",
+		"comment.author":          "作者",
+		"comment.body":            "内容",
+		"comment.mentions":        "提及",
+		"comment.time":            "时间",
+
+		// 附件
+		"ATTACHMENT_NOT_FOUND":        "附件不存在",
+		"ATTACHMENT_FILE_MISSING":     "附件文件不存在",
+		"ATTACHMENT_FILE_REQUIRED":    "请通过 file 字段上传文件",
+		"ATTACHMENT_TOO_LARGE":        "附件大小不能超过 %d MB",
+		"ATTACHMENT_TYPE_NOT_ALLOWED": "不允许上传该类型的文件: %s",
+		"ATTACHMENT_UPLOAD_FAILED":    "上传附件失败",
+		"ATTACHMENT_DOWNLOAD_FAILED":  "下载附件失败",
+		"ATTACHMENT_DELETED":          "附件删除成功",
+		"DELETE_ATTACHMENTS_FAILED":   "删除附件失败",
+		"DELETE_NOTIFICATIONS_FAILED": "删除通知失败",
+		"DELETE_VIEWS_FAILED":         "更新项目相关的视图失败",
+
+		// 头像
+		"AVATAR_NOT_FOUND":            "该成员没有上传头像",
+		"AVATAR_TOO_LARGE":            "头像文件不能超过 %d MB",
+		"AVATAR_INVALID_IMAGE":        "无法识别的图片，支持 PNG、JPEG、GIF、WebP",
+		"AVATAR_DIMENSIONS_TOO_LARGE": "图片尺寸不能超过 %d×%d",
+		"AVATAR_UPLOAD_FAILED":        "上传头像失败",
+		"AVATAR_DOWNLOAD_FAILED":      "读取头像失败",
+
+		// 工时
+		"TIME_ENTRY_NOT_FOUND":       "工时记录不存在",
+		"TIME_ENTRY_DELETED":         "工时记录删除成功",
+		"DELETE_TIME_ENTRIES_FAILED": "删除工时记录失败",
+		"DELETE_SNAPSHOTS_FAILED":    "删除任务进度快照失败",
+		"DELETE_WEBHOOKS_FAILED":     "删除Webhook失败",
+		"MEMBER_NOT_IN_PROJECT":      "成员不属于任务所在的项目",
+		"DAILY_HOURS_EXCEEDED":       "同一天的工时合计不能超过 %v 小时（已记录 %v 小时）",
+		"sheet.hours":                "工时",
+		"hours.member":               "成员",
+		"hours.total":                "合计",
+		"hours.actual_total":         "实际工时合计",
+		"hours.estimated_total":      "预估工时合计",
+
+		// 挣值分析
+		"sheet.evm":       "挣值分析",
+		"evm.status_date": "状态日期",
+		"evm.date":        "日期",
+		"evm.bac":         "完工预算 (BAC)",
+		"evm.pv":          "计划价值 (PV)",
+		"evm.ev":          "挣值 (EV)",
+		"evm.ac":          "实际成本 (AC)",
+		"evm.sv":          "进度偏差 (SV)",
+		"evm.cv":          "成本偏差 (CV)",
+		"evm.spi":         "进度绩效指数 (SPI)",
+		"evm.cpi":         "成本绩效指数 (CPI)",
+		"evm.eac":         "完工估算 (EAC)",
+		"evm.etc":         "完工尚需估算 (ETC)",
+		"evm.vac":         "完工偏差 (VAC)",
+		"evm.chart_title": "S曲线",
+
+		// 成本
+		"col.planned_cost":  "计划成本",
+		"col.actual_cost":   "实际成本",
+		"col.cost_variance": "成本偏差",
+
+		// 我的任务导出
+		"sheet.my_tasks":      "我的任务",
+		"mytasks.flag":        "提醒",
+		"mytasks.overdue":     "已逾期",
+		"mytasks.due_soon":    "即将到期",
+		"mytasks.file_suffix": "_我的任务",
+	},
+	"en-US": {
+		// 错误信息
+		"INVALID_REQUEST_BODY":          "Invalid request body",
+		"INVALID_PARAMETER":             "Invalid request parameter",
+		"SEARCH_QUERY_REQUIRED":         "Search query is required",
+		"SEARCH_MEMBER_REQUIRED":        "The member parameter (member email) is required; only that member's projects are searched",
+		"VIEW_NOT_FOUND":                "View not found",
+		"VIEW_NAME_TAKEN":               "A view with this name already exists",
+		"VIEW_DELETED":                  "View deleted successfully",
+		"VALIDATION_FAILED":             "Validation failed",
+		"INVALID_FIELD":                 "Invalid field value",
+		"INVALID_TYPE":                  "Invalid field type, expected %s",
+		"DATABASE_ERROR":                "Database operation failed",
+		"RESOURCE_NOT_FOUND":            "Resource not found",
+		"DUPLICATE_RESOURCE":            "Resource already exists",
+		"RESOURCE_IN_USE":               "Resource is still referenced by other data",
+		"REFERENCED_RESOURCE_NOT_FOUND": "Referenced resource does not exist",
+		"CONSTRAINT_VIOLATION":          "Data violates a constraint",
+		"FIELD_REQUIRED":                "is required",
+		"INVALID_ENUM":                  "invalid value, allowed values: %s",
+		"OUT_OF_RANGE":                  "must be between %v and %v",
+		"INVALID_DATE":                  "invalid date format",
+		"END_BEFORE_START":              "end date must not be before start date",
+		"OUTSIDE_PROJECT_RANGE":         "must be within the project dates (%s to %s)",
+		"OUTSIDE_STAGE_RANGE":           "must be within the stage dates (%s to %s)",
+		"STAGES_OUTSIDE_RANGE":          "existing stages fall outside this range (%s)",
+		"TASKS_OUTSIDE_RANGE":           "existing tasks fall outside this range (%s)",
+		"REFERENCE_NOT_FOUND":           "referenced record does not exist: %v",
+		"ASSIGNEE_NOT_IN_PROJECT":       "assignee is not a member of this project",
+		"INVALID_EMAIL":                 "invalid email address",
+		"INVALID_URL":                   "invalid URL, must be an http or https address",
+		"UNKNOWN_EVENT":                 "unknown event type: %s",
+		"DUPLICATE_ID":                  "duplicate id: %v",
+		"MISSING_ID":                    "missing id: %v",
+		"FIELD_NOT_PATCHABLE":           "field cannot be modified",
+		"PATCH_NOT_OBJECT":              "Patch must be a JSON object",
+		"IF_MATCH_REQUIRED":             "If-Match header is required",
+		"VERSION_CONFLICT":              "The data was modified by someone else, please reload and try again",
+		"VERSION_REQUIRED":              "version is required when operating on an existing task",
+		"INVALID_BULK_SIZE":             "Number of operations must be between 1 and %d",
+		"INVALID_BULK_OP":               "Unsupported bulk operation: %s",
+		"BULK_TARGET_REQUIRED":          "Target id is required",
+		"BULK_OPERATION_FAILED":         "Operation %d failed, all operations were rolled back",
+		"PROJECT_NOT_FOUND":             "Project not found",
+		"STAGE_NOT_FOUND":               "Stage not found",
+		"TASK_NOT_FOUND":                "Task not found",
+		"MEMBER_NOT_FOUND":              "Team member not found",
+		"EXPORT_TEMPLATE_NOT_FOUND":     "Export template not found",
+		"WEBHOOK_NOT_FOUND":             "Webhook not found",
+		"WEBHOOK_DELIVERY_NOT_FOUND":    "Webhook delivery not found",
+		"WEBHOOK_SECRET_FAILED":         "Failed to generate webhook secret",
+		"INVALID_DATE_RANGE":            "Start date must not be after end date",
+		"INVALID_START_DATE":            "Invalid start date",
+		"INVALID_END_DATE":              "Invalid end date",
+		"INVALID_ID":                    "Invalid ID parameter: %s",
+		"DELETE_TASKS_FAILED":           "Failed to delete related tasks",
+		"DELETE_STAGES_FAILED":          "Failed to delete related stages",
+		"DELETE_MEMBERS_FAILED":         "Failed to delete related team members",
+		"DELETE_PROJECT_FAILED":         "Failed to delete project",
+		"DELETE_FAILED":                 "Delete operation failed",
+		"PROJECT_LOAD_FAILED":           "Failed to load project",
+		"EXPORT_TEMPLATE_LOAD_FAILED":   "Failed to load export template",
+		"EXCEL_GENERATION_FAILED":       "Failed to generate Excel file",
+		"INVALID_LAYOUT":                "Invalid layout",
+		"TEMPLATE_NAME_REQUIRED":        "Template name is required",
+		"UNSUPPORTED_LOCALE":            "Unsupported locale: %s",
+		"UNKNOWN_SHEET":                 "Unknown sheet: %s",
+		"DUPLICATE_SHEET":               "Duplicate sheet: %s",
+		"INVALID_SHEET_NAME":            "Invalid sheet name: %s",
+		"DUPLICATE_SHEET_NAME":          "Duplicate sheet name: %s",
+		"UNKNOWN_COLUMN":                "Unknown column: %s",
+
+		// 提示信息
+		"SERVICE_OK":              "Gantt backend service is running",
+		"PROJECT_DELETED":         "Project deleted successfully",
+		"EXPORT_TEMPLATE_DELETED": "Export template deleted successfully",
+		"WEBHOOK_DELETED":         "Webhook deleted successfully",
+
+		// 导出文字
+		"title":              "Project Gantt Chart",
+		"file_suffix":        "_gantt",
+		"sheet.overview":     "Gantt",
+		"sheet.data":         "Gantt Data",
+		"sheet.timeline":     "Timeline",
+		"sheet.members":      "Team Members",
+		"info.name":          "Project",
+		"info.description":   "Description",
+		"info.start_date":    "Start Date",
+		"info.end_date":      "End Date",
+		"info.status":        "Status",
+		"col.project":        "Project",
+		"col.stage":          "Stage",
+		"col.task":           "Task",
+		"col.name":           "Stage/Task",
+		"col.start_date":     "Start Date",
+		"col.end_date":       "End Date",
+		"col.duration":       "Duration (workdays)",
+		"col.status":         "Status",
+		"col.progress":       "Progress",
+		"col.assignee":       "Assignee",
+		"col.priority":       "Priority",
+		"timeline.name":      "Task/Stage",
+		"member.name":        "Name",
+		"member.role":        "Role",
+		"member.email":       "Email",
+		"member.avatar":      "Avatar",
+		"member.joined":      "Joined",
+		"member.status":      "Status",
+		"member.active":      "Active",
+		"member.inactive":    "Inactive",
+		"status.pending":     "Pending",
+		"status.in_progress": "In Progress",
+		"status.completed":   "Completed",
+		"status.active":      "Active",
+		"status.paused":      "Paused",
+		"priority.low":       "Low",
+		"priority.medium":    "Medium",
+		"priority.high":      "High",
+		"priority.urgent":    "Urgent",
+		"portfolio.file":     "portfolio",
+		"sheet.portfolio":    "Portfolio",
+		"sheet.combined":     "Portfolio Timeline",
+		"col.task_count":     "Tasks",
+		"col.overdue":        "Overdue Tasks",
+
+		// 通知和邮件
+		"NOTIFICATION_NOT_FOUND":    "Notification not found",
+		"notify.task_overdue":       "Task \"%s\" was due on %s and is not completed",
+		"notify.task_due_soon":      "Task \"%s\" is due on %s",
+		"notify.stage_overdue":      "Stage \"%s\" was due on %s and is not completed",
+		"notify.stage_due_soon":     "Stage \"%s\" is due on %s",
+		"notify.task_assigned":      "Task \"%s\" has been assigned to you, due on %s",
+		"notify.task_unassigned":    "Task \"%[1]s\" has been reassigned to someone else",
+		"notify.task_rescheduled":   "Task \"%s\" has been rescheduled and is now due on %s",
+		"notify.task_completed":     "Task \"%[1]s\" has been completed",
+		"mail.notification_subject": "[Gantt] You have %d new notification(s)",
+		"mail.digest_subject":       "[Gantt] Notification digest for %s",
+
+		// 评论
+		"COMMENT_NOT_FOUND":       "Comment not found",
+		"COMMENT_DELETED":         "Comment deleted successfully",
+		"DELETE_COMMENTS_FAILED":  "Failed to delete comments",
+		"COMMENT_TARGET_REQUIRED": "exactly one of task_id or stage_id is required",
+		"AUTHOR_NOT_IN_PROJECT":   "author is not a member of this project",
+		"notify.comment_mention":  "You were mentioned in a comment on \"%[1]s\"",
+		"sheet.comments":          "Comments",
+		"comment.type":            "Type",
+		"comment.target":          "Name",
+		"comment.author":          "Author",
+		"comment.body":            "Comment",
+		"comment.mentions":        "Mentions",
+		"comment.time":            "Time",
+
+		// 附件
+		"ATTACHMENT_NOT_FOUND":        "Attachment not found",
+		"ATTACHMENT_FILE_MISSING":     "Attachment file is missing",
+		"ATTACHMENT_FILE_REQUIRED":    "Upload the file in the \"file\" form field",
+		"ATTACHMENT_TOO_LARGE":        "Attachments must not exceed %d MB",
+		"ATTACHMENT_TYPE_NOT_ALLOWED": "File type not allowed: %s",
+		"ATTACHMENT_UPLOAD_FAILED":    "Failed to upload attachment",
+		"ATTACHMENT_DOWNLOAD_FAILED":  "Failed to download attachment",
+		"ATTACHMENT_DELETED":          "Attachment deleted successfully",
+		"DELETE_ATTACHMENTS_FAILED":   "Failed to delete attachments",
+		"DELETE_NOTIFICATIONS_FAILED": "Failed to delete notifications",
+		"DELETE_VIEWS_FAILED":         "Failed to update views referencing the project",
+
+		// 头像
+		"AVATAR_NOT_FOUND":            "This member has no uploaded avatar",
+		"AVATAR_TOO_LARGE":            "Avatar files must not exceed %d MB",
+		"AVATAR_INVALID_IMAGE":        "Unrecognized image, PNG, JPEG, GIF and WebP are supported",
+		"AVATAR_DIMENSIONS_TOO_LARGE": "Images must not exceed %d×%d pixels",
+		"AVATAR_UPLOAD_FAILED":        "Failed to upload avatar",
+		"AVATAR_DOWNLOAD_FAILED":      "Failed to read avatar",
+
+		// 工时
+		"TIME_ENTRY_NOT_FOUND":       "Time entry not found",
+		"TIME_ENTRY_DELETED":         "Time entry deleted successfully",
+		"DELETE_TIME_ENTRIES_FAILED": "Failed to delete time entries",
+		"DELETE_SNAPSHOTS_FAILED":    "Failed to delete task progress snapshots",
+		"DELETE_WEBHOOKS_FAILED":     "Failed to delete webhooks",
+		"MEMBER_NOT_IN_PROJECT":      "member does not belong to the task's project",
+		"DAILY_HOURS_EXCEEDED":       "hours logged on one day must not exceed %v (already logged %v)",
+		"sheet.hours":                "Hours",
+		"hours.member":               "Member",
+		"hours.total":                "Total",
+		"hours.actual_total":         "Actual hours",
+		"hours.estimated_total":      "Estimated hours",
+
+		// 挣值分析
+		"sheet.evm":       "Earned Value",
+		"evm.status_date": "Status date",
+		"evm.date":        "Date",
+		"evm.bac":         "Budget at completion (BAC)",
+		"evm.pv":          "Planned value (PV)",
+		"evm.ev":          "Earned value (EV)",
+		"evm.ac":          "Actual cost (AC)",
+		"evm.sv":          "Schedule variance (SV)",
+		"evm.cv":          "Cost variance (CV)",
+		"evm.spi":         "Schedule performance index (SPI)",
+		"evm.cpi":         "Cost performance index (CPI)",
+		"evm.eac":         "Estimate at completion (EAC)",
+		"evm.etc":         "Estimate to complete (ETC)",
+		"evm.vac":         "Variance at completion (VAC)",
+		"evm.chart_title": "S-curve",
+
+		// 成本
+		"col.planned_cost":  "Planned Cost",
+		"col.actual_cost":   "Actual Cost",
+		"col.cost_variance": "Cost Variance",
+
+		// 我的任务导出
+		"sheet.my_tasks":      "My Tasks",
+		"mytasks.flag":        "Alert",
+		"mytasks.overdue":     "Overdue",
+		"mytasks.due_soon":    "Due soon",
+		"mytasks.file_suffix": "_tasks",
+	},
+}
+
+// 根据 ?lang= 或 Accept-Language 确定请求语言
+func localeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale := matchLocale(c.Query("lang"))
+		if locale == "" {
+			locale = parseAcceptLanguage(c.GetHeader("Accept-Language"))
+		}
+		c.Set(localeContextKey, locale)
+		c.Header("Content-Language", locale)
+		c.Next()
+	}
+}
+
+// 获取请求语言
+func getLocale(c *gin.Context) string {
+	if locale := c.GetString(localeContextKey); locale != "" {
+		return locale
+	}
+	return defaultLocale
+}
+
+// 解析 Accept-Language，按q值从高到低选择第一个支持的语言
+func parseAcceptLanguage(header string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		if fields[0] == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q <= 0 { // q=0 表示不接受该语言
+			continue
+		}
+		tags = append(tags, weighted{fields[0], q})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	for _, tag := range tags {
+		if locale := matchLocale(tag.tag); locale != "" {
+			return locale
+		}
+	}
+	return defaultLocale
+}
+
+// 将语言标签匹配到支持的语言，按主语言匹配，如 en、en-GB 匹配 en-US
+func matchLocale(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return ""
+	}
+	primary := strings.SplitN(strings.ReplaceAll(tag, "_", "-"), "-", 2)[0]
+	for _, locale := range supportedLocales {
+		if strings.ToLower(locale) == tag {
+			return locale
+		}
+	}
+	for _, locale := range supportedLocales {
+		if strings.HasPrefix(strings.ToLower(locale), primary+"-") {
+			return locale
+		}
+	}
+	return ""
+}
+
+func isSupportedLocale(locale string) bool {
+	_, ok := translations[locale]
+	return ok
+}
+
+// 翻译文字，缺少翻译时依次回退到默认语言和键本身
+func T(locale, key string, args ...interface{}) string {
+	text, exists := translations[locale][key]
+	if !exists {
+		if text, exists = translations[defaultLocale][key]; !exists {
+			text = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+// 返回翻译后的提示信息
+func respondMessage(c *gin.Context, status int, code string) {
+	c.JSON(status, gin.H{"code": code, "message": T(getLocale(c), code)})
+}
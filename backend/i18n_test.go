@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", defaultLocale},
+		{"en-US", "en-US"},
+		{"en", "en-US"},
+		{"en-GB,en;q=0.8", "en-US"},
+		{"zh_CN", "zh-CN"},
+		{"ZH-cn", "zh-CN"},
+		{"zh-TW", "zh-CN"},
+		{"fr-FR,en;q=0.5", "en-US"},
+		{"fr-FR,de;q=0.9", defaultLocale},
+		{"zh-CN;q=0.5, en-US;q=0.9", "en-US"},
+		{"en;q=0.8, zh-CN;q=0.8", "en-US"},
+		{"en;q=0, zh-CN;q=0.1", "zh-CN"},
+		{"en;q=0", defaultLocale},
+		{"en;q=abc, zh-CN;q=0.5", "en-US"},
+		{" , ;q=0.9, en-US", "en-US"},
+		{"*", defaultLocale},
+	}
+	for _, tt := range tests {
+		if got := parseAcceptLanguage(tt.header); got != tt.want {
+			t.Errorf("parseAcceptLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestMatchLocale(t *testing.T) {
+	tests := map[string]string{
+		"zh-CN":   "zh-CN",
+		"en-us":   "en-US",
+		"en_AU":   "en-US",
+		"zh":      "zh-CN",
+		" en ":    "en-US",
+		"fr":      "",
+		"english": "",
+		"":        "",
+	}
+	for tag, want := range tests {
+		if got := matchLocale(tag); got != want {
+			t.Errorf("matchLocale(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"text/template"
+	"time"
+)
+
+// 通过SMTP中继发送邮件
+type Mailer struct {
+	addr   string
+	from   string
+	auth   smtp.Auth
+	Locale string // 邮件语言
+}
+
+// 未配置 SMTP_HOST 时为 nil，不发送邮件
+var mailer *Mailer
+
+func InitMailer(config *Config) {
+	if config.SMTPHost == "" {
+		log.Println("未配置SMTP，不发送邮件通知")
+		return
+	}
+
+	m := &Mailer{
+		addr:   net.JoinHostPort(config.SMTPHost, config.SMTPPort),
+		from:   config.SMTPFrom,
+		Locale: matchLocale(config.MailLocale),
+	}
+	if m.from == "" {
+		m.from = config.SMTPUser
+	}
+	if m.Locale == "" {
+		m.Locale = defaultLocale
+	}
+	if config.SMTPUser != "" {
+		m.auth = smtp.PlainAuth("", config.SMTPUser, config.SMTPPassword, config.SMTPHost)
+	}
+	mailer = m
+	log.Printf("邮件通过 %s 发送", m.addr)
+}
+
+// 成员设置了语言时使用成员的语言
+func (m *Mailer) localeFor(member *TeamMember) string {
+	if isSupportedLocale(member.Locale) {
+		return member.Locale
+	}
+	return m.Locale
+}
+
+// 邮件模板数据
+type mailData struct {
+	Name  string
+	Date  string
+	Items []string
+}
+
+// 各语言的邮件模板：notification 为即时通知，digest 为每日汇总
+var mailTemplates = map[string]*template.Template{
+	"zh-CN": template.Must(template.New("zh-CN").Parse(`{{define "notification"}}{{.Name}}，您好：
+
+{{range .Items}}- {{.}}
+{{end}}
+此邮件由甘特图系统自动发送，如需改为每日汇总或关闭邮件，请在团队成员设置中修改。
+{{end}}{{define "digest"}}{{.Name}}，您好：
+
+以下是截至 {{.Date}} 的通知汇总，共 {{len .Items}} 条：
+
+{{range .Items}}- {{.}}
+{{end}}
+此邮件由甘特图系统自动发送，如需改为即时通知或关闭邮件，请在团队成员设置中修改。
+{{end}}`)),
+	"en-US": template.Must(template.New("en-US").Parse(`{{define "notification"}}Hi {{.Name}},
+
+{{range .Items}}- {{.}}
+{{end}}
+This email was sent automatically by Gantt. To switch to a daily digest or turn emails off, update your team member settings.
+{{end}}{{define "digest"}}Hi {{.Name}},
+
+Here is your summary up to {{.Date}} ({{len .Items}} item(s)):
+
+{{range .Items}}- {{.}}
+{{end}}
+This email was sent automatically by Gantt. To switch to instant emails or turn emails off, update your team member settings.
+{{end}}`)),
+}
+
+func renderMail(locale, name string, data mailData) (string, error) {
+	tpl, ok := mailTemplates[locale]
+	if !ok {
+		tpl = mailTemplates[defaultLocale]
+	}
+	var body bytes.Buffer
+	if err := tpl.ExecuteTemplate(&body, name, data); err != nil {
+		return "", err
+	}
+	return body.String(), nil
+}
+
+// 发送纯文本邮件
+func (m *Mailer) Send(to, subject, body string) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(body)
+
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{to}, msg.Bytes())
+}
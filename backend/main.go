@@ -14,6 +14,19 @@ func main() {
 	// 初始化数据库
 	InitDatabase(config)
 
+	// 初始化事件代理
+	InitEvents(config)
+
+	// 启动Webhook投递
+	InitWebhooks()
+
+	// 初始化邮件和截止提醒
+	InitMailer(config)
+	InitScheduler(config)
+
+	// 初始化附件存储
+	InitStorage(config)
+
 	// 创建Gin引擎
 	r := gin.Default()
 
@@ -24,7 +37,7 @@ func main() {
 	// 配置CORS
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"*"},
 		ExposeHeaders:    []string{"*"},
 		AllowCredentials: false,
@@ -37,11 +50,14 @@ func main() {
 		c.Next()
 	})
 
+	// 根据请求确定响应语言
+	r.Use(localeMiddleware())
+
 	// 健康检查 - 放在最前面
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"status":  "ok",
-			"message": "咸鱼甘特图后端服务运行正常",
+			"message": T(getLocale(c), "SERVICE_OK"),
 		})
 	})
 
@@ -53,26 +69,99 @@ func main() {
 		api.POST("/projects", createProject)
 		api.GET("/projects/:id", getProject)
 		api.PUT("/projects/:id", updateProject)
+		api.PATCH("/projects/:id", patchProject)
 		api.DELETE("/projects/:id", deleteProject)
 		api.GET("/projects/:id/export", exportProjectToExcel)
+		api.GET("/projects/:id/evm", getProjectEVM)
+		api.GET("/projects/:id/costs", getProjectCosts)
+		api.GET("/projects/:id/burnup", getProjectBurnup)
+		api.GET("/projects/:id/stats", getProjectStats)
+		api.GET("/projects/:id/events", streamProjectEvents)
+
+		// 项目组合路由
+		api.GET("/portfolio/export", exportPortfolioToExcel)
+
+		// 全局统计路由
+		api.GET("/stats", getStats)
+
+		// 搜索路由
+		api.GET("/search", search)
+
+		// 已保存视图路由
+		api.GET("/views", getSavedViews)
+		api.POST("/views", createSavedView)
+		api.PUT("/views/:id", updateSavedView)
+		api.DELETE("/views/:id", deleteSavedView)
 
 		// 项目阶段路由
 		api.POST("/stages", createStage)
 		api.GET("/stages/project/:projectId", getStages)
+		api.PUT("/stages/project/:projectId/order", reorderStages)
 		api.PUT("/stages/:id", updateStage)
+		api.PATCH("/stages/:id", patchStage)
+		api.GET("/stages/:id/burndown", getStageBurndown)
 
 		// 项目团队成员路由
 		api.POST("/members", createTeamMember)
 		api.GET("/members/project/:projectId", getTeamMembers)
 		api.PUT("/members/:id", updateTeamMember)
+		api.PATCH("/members/:id", patchTeamMember)
+		api.POST("/members/:id/avatar", uploadAvatar)
+		api.GET("/members/:id/avatar", getAvatar)
+		api.DELETE("/members/:id/avatar", deleteAvatar)
+		api.GET("/members/:id/timesheet", getMemberTimesheet)
+		api.GET("/members/:id/tasks", getMemberTasks)
+		api.GET("/members/:id/tasks/export", exportMemberTasks)
 
 		// 任务路由
+		api.GET("/tasks", queryTasks)
 		api.POST("/tasks", createTask)
+		api.POST("/tasks/bulk", bulkTasks)
+		api.PUT("/tasks/stage/:stageId/order", reorderTasks)
 		api.PUT("/tasks/:id", updateTask)
+		api.PATCH("/tasks/:id", patchTask)
+
+		// 评论路由
+		api.GET("/comments/task/:taskId", getTaskComments)
+		api.GET("/comments/stage/:stageId", getStageComments)
+		api.POST("/comments", createComment)
+		api.PUT("/comments/:id", updateComment)
+		api.DELETE("/comments/:id", deleteComment)
+
+		// 附件路由
+		api.POST("/attachments/task/:taskId", uploadTaskAttachment)
+		api.POST("/attachments/stage/:stageId", uploadStageAttachment)
+		api.GET("/attachments/:id/download", downloadAttachment)
+		api.DELETE("/attachments/:id", deleteAttachment)
+
+		// 工时路由
+		api.GET("/time-entries/task/:taskId", getTaskTimeEntries)
+		api.POST("/time-entries", createTimeEntry)
+		api.PUT("/time-entries/:id", updateTimeEntry)
+		api.DELETE("/time-entries/:id", deleteTimeEntry)
 
 		// 角色路由
 		api.GET("/roles", getRoles)
 
+		// 导出模板路由
+		api.GET("/export-templates", getExportTemplates)
+		api.POST("/export-templates", createExportTemplate)
+		api.PUT("/export-templates/:id", updateExportTemplate)
+		api.DELETE("/export-templates/:id", deleteExportTemplate)
+
+		// Webhook路由
+		api.GET("/webhooks/project/:projectId", getWebhooks)
+		api.POST("/webhooks", createWebhook)
+		api.PUT("/webhooks/:id", updateWebhook)
+		api.DELETE("/webhooks/:id", deleteWebhook)
+		api.GET("/webhooks/:id/deliveries", getWebhookDeliveries)
+		api.POST("/webhooks/deliveries/:id/redeliver", redeliverWebhook)
+
+		// 通知路由
+		api.GET("/notifications", getNotifications)
+		api.PUT("/notifications/read-all", markAllNotificationsRead)
+		api.PUT("/notifications/:id/read", markNotificationRead)
+
 		// 甘特图数据路由
 		api.GET("/gantt/:projectId", getGanttData)
 	}
@@ -11,13 +11,15 @@ type Project struct {
 	Description string    `json:"description"`
 	StartDate   time.Time `json:"start_date"`
 	EndDate     time.Time `json:"end_date"`
-	Status      string    `gorm:"default:active" json:"status"` // active, completed, paused
+	Status      string    `gorm:"default:active" json:"status"`      // active, completed, paused
+	Version     uint      `gorm:"not null;default:1" json:"version"` // 乐观锁版本号，每次更新加1
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
 	// 关联关系
 	Stages      []Stage      `json:"stages,omitempty"`
 	TeamMembers []TeamMember `json:"team_members,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
 }
 
 // 项目阶段表
@@ -31,6 +33,7 @@ type Stage struct {
 	Status      string    `gorm:"default:pending" json:"status"` // pending, in_progress, completed
 	Order       int       `gorm:"default:0" json:"order"`
 	Progress    float64   `gorm:"default:0" json:"progress"` // 0-100
+	Version     uint      `gorm:"not null;default:1" json:"version"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
@@ -43,18 +46,23 @@ type Stage struct {
 
 // 任务表
 type Task struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	StageID     uint      `gorm:"not null" json:"stage_id"`
-	Name        string    `gorm:"not null" json:"name"`
-	Description string    `json:"description"`
-	StartDate   time.Time `json:"start_date"`
-	EndDate     time.Time `json:"end_date"`
-	Status      string    `gorm:"default:pending" json:"status"`  // pending, in_progress, completed
-	Priority    string    `gorm:"default:medium" json:"priority"` // low, medium, high, urgent
-	Progress    float64   `gorm:"default:0" json:"progress"`      // 0-100
-	AssignedTo  uint      `json:"assigned_to"`                    // 关联到团队成员
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	StageID        uint      `gorm:"not null" json:"stage_id"`
+	Name           string    `gorm:"not null" json:"name"`
+	Description    string    `json:"description"`
+	StartDate      time.Time `json:"start_date"`
+	EndDate        time.Time `json:"end_date"`
+	Status         string    `gorm:"default:pending" json:"status"`    // pending, in_progress, completed
+	Priority       string    `gorm:"default:medium" json:"priority"`   // low, medium, high, urgent
+	Order          int       `gorm:"default:0" json:"order"`           // 阶段内排序
+	Progress       float64   `gorm:"default:0" json:"progress"`        // 0-100
+	AssignedTo     uint      `json:"assigned_to"`                      // 关联到团队成员
+	EstimatedHours float64   `gorm:"default:0" json:"estimated_hours"` // 预估工时（小时）
+	PlannedCost    float64   `gorm:"default:0" json:"planned_cost"`    // 计划成本
+	ActualCost     *float64  `json:"actual_cost"`                      // 手工填写的实际成本，为空时按工时记录计算
+	Version        uint      `gorm:"not null;default:1" json:"version"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 
 	// 外键关系
 	Stage    Stage      `gorm:"foreignKey:StageID" json:"stage,omitempty"`
@@ -63,15 +71,20 @@ type Task struct {
 
 // 团队成员表
 type TeamMember struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	ProjectID uint      `gorm:"not null" json:"project_id"`
-	Name      string    `gorm:"not null" json:"name"`
-	Email     string    `json:"email"`
-	Role      string    `gorm:"not null" json:"role"` // PM, PO, frontend, backend, ui, vfx, audio, tester
-	Avatar    string    `json:"avatar"`
-	IsActive  bool      `gorm:"default:true" json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ProjectID  uint      `gorm:"not null" json:"project_id"`
+	Name       string    `gorm:"not null" json:"name"`
+	Email      string    `json:"email"`
+	Role       string    `gorm:"not null" json:"role"` // PM, PO, frontend, backend, ui, vfx, audio, tester
+	Avatar     string    `json:"avatar"`               // 头像地址，上传头像后为本服务的地址
+	AvatarKey  string    `json:"-"`                    // 上传的头像在附件存储中的路径
+	IsActive   bool      `gorm:"default:true" json:"is_active"`
+	HourlyRate float64   `gorm:"default:0" json:"hourly_rate"`               // 小时费率，用于挣值分析的成本计算
+	EmailMode  string    `gorm:"not null;default:instant" json:"email_mode"` // 邮件通知方式：instant, digest, off
+	Locale     string    `json:"locale"`                                     // 邮件语言，为空时使用 MAIL_LOCALE
+	Version    uint      `gorm:"not null;default:1" json:"version"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
 
 	// 外键关系
 	Project Project `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
@@ -89,6 +102,145 @@ type Role struct {
 	Description string `json:"description"`
 }
 
+// Excel导出模板
+type ExportTemplate struct {
+	ID              uint              `gorm:"primaryKey" json:"id"`
+	Name            string            `gorm:"not null;unique" json:"name"`
+	Description     string            `json:"description"`
+	Locale          string            `json:"locale"`                                  // zh-CN, en-US，为空时跟随请求语言
+	Sheets          []string          `gorm:"serializer:json" json:"sheets"`           // overview, data, timeline, members, hours, comments
+	SheetNames      map[string]string `gorm:"serializer:json" json:"sheet_names"`      // 工作表名称覆盖
+	OverviewColumns []string          `gorm:"serializer:json" json:"overview_columns"` // "甘特图"表的列及顺序
+	DataColumns     []string          `gorm:"serializer:json" json:"data_columns"`     // "甘特图数据"表的列及顺序
+	Headers         map[string]string `gorm:"serializer:json" json:"headers"`          // 表头文字覆盖
+	StatusColors    map[string]string `gorm:"serializer:json" json:"status_colors"`    // 状态颜色覆盖
+	Title           string            `json:"title"`                                   // 为空时使用语言默认标题
+	HeaderColor     string            `gorm:"default:366092" json:"header_color"`      // 表头背景色
+	HeaderFontColor string            `gorm:"default:FFFFFF" json:"header_font_color"` // 表头文字颜色
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// Webhook订阅：项目事件以签名的JSON推送到指定URL
+type Webhook struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ProjectID uint      `gorm:"not null;index" json:"project_id"`
+	URL       string    `gorm:"not null" json:"url"`
+	Secret    string    `gorm:"not null" json:"secret,omitempty"`       // 签名密钥，只在创建时返回
+	Events    []string  `gorm:"serializer:json" json:"events"`          // 事件过滤，如 task.completed、task.*，为空时接收全部事件
+	IsActive  bool      `gorm:"not null;default:true" json:"is_active"` // 停用后不再投递
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Webhook投递记录
+type WebhookDelivery struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	WebhookID     uint       `gorm:"not null;index" json:"webhook_id"`
+	EventType     string     `gorm:"not null" json:"event_type"`
+	Payload       string     `gorm:"type:text;not null" json:"payload"`
+	Status        string     `gorm:"not null;default:pending;index" json:"status"` // pending, succeeded, failed
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	ResponseCode  int        `json:"response_code"`
+	ResponseBody  string     `gorm:"type:text" json:"response_body"`
+	Error         string     `gorm:"type:text" json:"error"`
+	NextAttemptAt time.Time  `gorm:"index" json:"next_attempt_at"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// 站内通知，按团队成员记录
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	MemberID  uint       `gorm:"not null;index" json:"member_id"`
+	ProjectID uint       `gorm:"not null;index" json:"project_id"`
+	TaskID    *uint      `json:"task_id,omitempty"`
+	StageID   *uint      `json:"stage_id,omitempty"`
+	Type      string     `gorm:"not null" json:"type"`    // task_overdue, task_due_soon, stage_overdue, stage_due_soon, task_assigned, task_unassigned, task_rescheduled, task_completed, comment_mention
+	Subject   string     `gorm:"not null" json:"subject"` // 任务或阶段名称
+	DueDate   time.Time  `json:"due_date"`
+	Message   string     `gorm:"-" json:"message"`              // 按请求语言生成
+	DedupKey  string     `gorm:"not null;uniqueIndex" json:"-"` // 同一事项的同一截止日期只通知一次
+	IsRead    bool       `gorm:"not null;default:false" json:"is_read"`
+	ReadAt    *time.Time `json:"read_at"`
+	EmailedAt *time.Time `json:"emailed_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// 评论，挂在任务或阶段下，正文为 Markdown
+type Comment struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	ProjectID uint       `gorm:"not null;index" json:"project_id"` // 由任务或阶段推导
+	TaskID    *uint      `gorm:"index" json:"task_id,omitempty"`
+	StageID   *uint      `gorm:"index" json:"stage_id,omitempty"`
+	AuthorID  uint       `gorm:"not null" json:"author_id"`
+	Body      string     `gorm:"type:text;not null" json:"body"`
+	EditedAt  *time.Time `json:"edited_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+
+	// 外键关系
+	Author TeamMember `gorm:"foreignKey:AuthorID" json:"author,omitempty"`
+
+	// 正文中 @ 到的成员
+	Mentions []TeamMember `gorm:"many2many:comment_mentions" json:"mentions"`
+}
+
+// 任务或阶段的附件，文件保存在附件存储中
+type Attachment struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ProjectID   uint      `gorm:"not null;index" json:"project_id"`
+	TaskID      *uint     `gorm:"index" json:"task_id,omitempty"`
+	StageID     *uint     `gorm:"index" json:"stage_id,omitempty"`
+	FileName    string    `gorm:"not null" json:"file_name"` // 上传时的原文件名
+	ContentType string    `gorm:"not null" json:"content_type"`
+	Size        int64     `gorm:"not null" json:"size"`
+	StorageKey  string    `gorm:"not null;uniqueIndex" json:"-"`
+	UploadedBy  uint      `json:"uploaded_by,omitempty"` // 上传的团队成员
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// 工时记录：成员某天在某个任务上花费的小时数
+type TimeEntry struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ProjectID uint      `gorm:"not null;index" json:"project_id"` // 由任务推导
+	TaskID    uint      `gorm:"not null;index" json:"task_id"`
+	MemberID  uint      `gorm:"not null;index:idx_time_entries_member_date" json:"member_id"`
+	Date      time.Time `gorm:"type:date;not null;index:idx_time_entries_member_date" json:"date"`
+	Hours     float64   `gorm:"not null" json:"hours"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// 外键关系
+	Task   Task       `gorm:"foreignKey:TaskID" json:"task,omitempty"`
+	Member TeamMember `gorm:"foreignKey:MemberID" json:"member,omitempty"`
+}
+
+// 任务进度每日快照，用于燃尽图和燃起图
+type TaskSnapshot struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ProjectID uint      `gorm:"not null;index" json:"project_id"`
+	StageID   uint      `gorm:"not null;index" json:"stage_id"`
+	TaskID    uint      `gorm:"not null;uniqueIndex:idx_task_snapshots_task_date" json:"task_id"`
+	Date      time.Time `gorm:"type:date;not null;uniqueIndex:idx_task_snapshots_task_date" json:"date"`
+	Status    string    `json:"status"`
+	Progress  float64   `json:"progress"`
+	Work      float64   `json:"work"`                                  // 当天的任务工作量（工期工作日数）
+	Deleted   bool      `gorm:"not null;default:false" json:"deleted"` // 任务已删除，之后不再计入工作量
+}
+
+// 保存的任务筛选视图，没有账号体系，所有者由客户端提供（如邮箱）
+type SavedView struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Name      string     `gorm:"not null;uniqueIndex:idx_saved_views_owner_name" json:"name"`
+	Owner     string     `gorm:"not null;uniqueIndex:idx_saved_views_owner_name" json:"owner"`
+	Filter    TaskFilter `gorm:"serializer:json" json:"filter"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
 // 预定义角色数据
 func GetDefaultRoles() []Role {
 	return []Role{
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// 多少个工作日内到期算即将到期，由 InitScheduler 按 REMINDER_DAYS 设置
+var dueSoonWorkDays = 2
+
+// "我的任务"导出表的列
+var myTaskColumns = []string{"project", "stage", "task", "start_date", "end_date", "duration", "status", "progress", "priority"}
+
+type myTask struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	Status    string    `json:"status"`
+	Priority  string    `json:"priority"`
+	Progress  float64   `json:"progress"`
+	Overdue   bool      `json:"overdue"`
+	DueSoon   bool      `json:"due_soon"`
+
+	task Task
+}
+
+type myStageTasks struct {
+	StageID uint     `json:"stage_id"`
+	Name    string   `json:"name"`
+	Tasks   []myTask `json:"tasks"`
+}
+
+type myProjectTasks struct {
+	ProjectID uint            `json:"project_id"`
+	Name      string          `json:"name"`
+	Status    string          `json:"status"`
+	Stages    []*myStageTasks `json:"stages"`
+}
+
+type myTasksReport struct {
+	Name      string            `json:"name"`
+	Email     string            `json:"email"`
+	MemberIDs []uint            `json:"member_ids"` // 同一邮箱在各项目中的成员ID
+	Total     int               `json:"total"`
+	Overdue   int               `json:"overdue"`
+	DueSoon   int               `json:"due_soon"`
+	Projects  []*myProjectTasks `json:"projects"`
+}
+
+// 成员在所有项目中的任务，按项目和阶段分组
+func getMemberTasks(c *gin.Context) {
+	report, err := loadMemberTasks(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// 导出成员在所有项目中的任务
+func exportMemberTasks(c *gin.Context) {
+	template, err := findExportTemplate(c.Query("template"), getLocale(c))
+	if err != nil {
+		respondError(c, dbError(err, "EXPORT_TEMPLATE_NOT_FOUND"))
+		return
+	}
+
+	report, err := loadMemberTasks(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("关闭Excel文件失败: %v", err)
+		}
+	}()
+
+	if err := buildMemberTasksWorkbook(f, report, template); err != nil {
+		log.Printf("生成Excel文件失败: %v", err)
+		respondError(c, internalError("EXCEL_GENERATION_FAILED", err))
+		return
+	}
+
+	fileName := report.Name + template.label("mytasks.file_suffix") + ".xlsx"
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+
+	if err := f.Write(c.Writer); err != nil {
+		log.Printf("写入Excel文件失败: %v", err)
+		respondError(c, internalError("EXCEL_GENERATION_FAILED", err))
+		return
+	}
+}
+
+// 按路径中的成员ID或邮箱找到此人在各项目中的成员记录，查询其负责的任务
+// 按ID查询时，成员填写了邮箱则同时包含同一邮箱在其他项目中的任务
+// 默认不含已完成的任务，include_completed=true 时包含
+func loadMemberTasks(c *gin.Context) (*myTasksReport, error) {
+	key := strings.TrimSpace(c.Param("id"))
+	report := &myTasksReport{Projects: []*myProjectTasks{}}
+
+	var members []TeamMember
+	if id, err := strconv.ParseUint(key, 10, 32); err == nil {
+		var member TeamMember
+		if err := DB.First(&member, id).Error; err != nil {
+			return nil, dbError(err, "MEMBER_NOT_FOUND")
+		}
+		report.Name, report.Email = member.Name, member.Email
+		members = []TeamMember{member}
+		if member.Email != "" {
+			if err := DB.Where("lower(email) = lower(?)", member.Email).Order("id").Find(&members).Error; err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if err := DB.Where("lower(email) = lower(?)", key).Order("id").Find(&members).Error; err != nil {
+			return nil, err
+		}
+		if len(members) == 0 {
+			return nil, notFound("MEMBER_NOT_FOUND")
+		}
+		report.Name, report.Email = members[0].Name, members[0].Email
+	}
+	for _, member := range members {
+		report.MemberIDs = append(report.MemberIDs, member.ID)
+	}
+
+	query := DB.Joins("JOIN stages ON stages.id = tasks.stage_id").Preload("Stage.Project").
+		Where("tasks.assigned_to IN ?", report.MemberIDs)
+	if c.Query("include_completed") != "true" {
+		query = query.Where("tasks.status <> ?", "completed")
+	}
+	var tasks []Task
+	if err := query.Order("stages.project_id").Order(`stages."order"`).Order("stages.id").
+		Order(`tasks."order"`).Order("tasks.id").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	today := dateOnly(time.Now())
+	dueLimit := addWorkDays(today, dueSoonWorkDays)
+	projects := map[uint]*myProjectTasks{}
+	stages := map[uint]*myStageTasks{}
+	for _, task := range tasks {
+		project := projects[task.Stage.ProjectID]
+		if project == nil {
+			project = &myProjectTasks{ProjectID: task.Stage.ProjectID, Name: task.Stage.Project.Name, Status: task.Stage.Project.Status}
+			projects[project.ProjectID] = project
+			report.Projects = append(report.Projects, project)
+		}
+		stage := stages[task.StageID]
+		if stage == nil {
+			stage = &myStageTasks{StageID: task.StageID, Name: task.Stage.Name}
+			stages[stage.StageID] = stage
+			project.Stages = append(project.Stages, stage)
+		}
+
+		item := myTask{
+			ID:        task.ID,
+			Name:      task.Name,
+			StartDate: task.StartDate,
+			EndDate:   task.EndDate,
+			Status:    task.Status,
+			Priority:  task.Priority,
+			Progress:  task.Progress,
+			Overdue:   isOverdue(task.EndDate, task.Status, today),
+			task:      task,
+		}
+		item.DueSoon = !item.Overdue && task.Status != "completed" && !task.EndDate.IsZero() && task.EndDate.Before(dueLimit.AddDate(0, 0, 1))
+		stage.Tasks = append(stage.Tasks, item)
+
+		report.Total++
+		if item.Overdue {
+			report.Overdue++
+		}
+		if item.DueSoon {
+			report.DueSoon++
+		}
+	}
+	return report, nil
+}
+
+// "我的任务"工作簿：一个表列出所有任务，最后一列标出逾期和即将到期
+func buildMemberTasksWorkbook(f *excelize.File, report *myTasksReport, tpl *ExportTemplate) error {
+	b, err := newWorkbookBuilder(f, tpl)
+	if err != nil {
+		return err
+	}
+
+	sheetName := tpl.sheetName("my_tasks")
+	if err := f.SetSheetName("Sheet1", sheetName); err != nil {
+		return err
+	}
+
+	overdueStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Color: "C00000"}})
+	if err != nil {
+		return err
+	}
+	dueSoonStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Color: "C65911"}})
+	if err != nil {
+		return err
+	}
+
+	b.writeHeaders(sheetName, 1, 1, myTaskColumns)
+	flagCol := getColumnLetter(len(myTaskColumns) + 1)
+	f.SetCellValue(sheetName, flagCol+"1", tpl.label("mytasks.flag"))
+	f.SetCellStyle(sheetName, flagCol+"1", flagCol+"1", b.headerStyle)
+	f.SetColWidth(sheetName, flagCol, flagCol, 12)
+
+	row := 2
+	for _, project := range report.Projects {
+		b.project = Project{ID: project.ProjectID, Name: project.Name}
+		for _, stage := range project.Stages {
+			for i := range stage.Tasks {
+				item := &stage.Tasks[i]
+				stageRow := Stage{ID: stage.StageID, Name: stage.Name}
+				b.writeRow(sheetName, 1, row, myTaskColumns, exportRow{Stage: &stageRow, Task: &item.task})
+
+				cell := flagCol + strconv.Itoa(row)
+				switch {
+				case item.Overdue:
+					f.SetCellValue(sheetName, cell, tpl.label("mytasks.overdue"))
+					f.SetCellStyle(sheetName, cell, cell, overdueStyle)
+				case item.DueSoon:
+					f.SetCellValue(sheetName, cell, tpl.label("mytasks.due_soon"))
+					f.SetCellStyle(sheetName, cell, cell, dueSoonStyle)
+				}
+				row++
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// 启动每日任务：启动时先检查一次截止日期，之后每天 ReminderHour 点检查并发送汇总邮件，ReminderDays 同时用于"我的任务"的即将到期标记
+func InitScheduler(config *Config) {
+	dueSoonWorkDays = config.ReminderDays
+	go func() {
+		checkDeadlines(time.Now(), config.ReminderDays)
+		snapshotTaskProgress(time.Now())
+		for {
+			timer := time.NewTimer(time.Until(nextDailyRun(time.Now(), config.ReminderHour)))
+			<-timer.C
+			checkDeadlines(time.Now(), config.ReminderDays)
+			sendDailyDigests(time.Now())
+			snapshotTaskProgress(time.Now())
+		}
+	}()
+}
+
+// 下一次在 hour 点运行的时间
+func nextDailyRun(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// 检查进行中项目里已逾期或将在 reminderDays 个工作日内到期的未完成任务和阶段，
+// 任务通知负责人（未分配时通知项目经理），阶段通知项目经理
+func checkDeadlines(now time.Time, reminderDays int) {
+	today := dateOnly(now)
+	dueLimit := addWorkDays(today, reminderDays)
+	log.Printf("开始检查截止日期，提醒截至 %s", dueLimit.Format("2006-01-02"))
+
+	var tasks []Task
+	if err := DB.Preload("Assignee").Preload("Stage").
+		Where("status <> ? AND end_date < ?", "completed", dueLimit.AddDate(0, 0, 1)).
+		Where("stage_id IN (SELECT stages.id FROM stages JOIN projects ON projects.id = stages.project_id WHERE projects.status = ?)", "active").
+		Find(&tasks).Error; err != nil {
+		log.Printf("查询待提醒任务失败: %v", err)
+		return
+	}
+
+	var stages []Stage
+	if err := DB.Where("status <> ? AND end_date < ?", "completed", dueLimit.AddDate(0, 0, 1)).
+		Where("project_id IN (SELECT id FROM projects WHERE status = ?)", "active").
+		Find(&stages).Error; err != nil {
+		log.Printf("查询待提醒阶段失败: %v", err)
+		return
+	}
+
+	managers := map[uint][]TeamMember{}
+	projectManagers := func(projectID uint) []TeamMember {
+		if members, ok := managers[projectID]; ok {
+			return members
+		}
+		var members []TeamMember
+		if err := DB.Where("project_id = ? AND role = ? AND is_active = ?", projectID, "pm", true).Find(&members).Error; err != nil {
+			log.Printf("查询项目 %d 的项目经理失败: %v", projectID, err)
+		}
+		managers[projectID] = members
+		return members
+	}
+
+	var created []Notification
+	for i := range tasks {
+		task := &tasks[i]
+		recipients := projectManagers(task.Stage.ProjectID)
+		if task.AssignedTo != 0 && task.Assignee.IsActive {
+			recipients = []TeamMember{task.Assignee}
+		}
+		kind := deadlineKind("task", task.EndDate, task.Status, today)
+		for _, member := range recipients {
+			n := Notification{MemberID: member.ID, ProjectID: task.Stage.ProjectID, TaskID: &task.ID,
+				Type: kind, Subject: task.Name, DueDate: dateOnly(task.EndDate)}
+			if recordNotification(&n, task.ID) {
+				created = append(created, n)
+			}
+		}
+	}
+	for i := range stages {
+		stage := &stages[i]
+		kind := deadlineKind("stage", stage.EndDate, stage.Status, today)
+		for _, member := range projectManagers(stage.ProjectID) {
+			n := Notification{MemberID: member.ID, ProjectID: stage.ProjectID, StageID: &stage.ID,
+				Type: kind, Subject: stage.Name, DueDate: dateOnly(stage.EndDate)}
+			if recordNotification(&n, stage.ID) {
+				created = append(created, n)
+			}
+		}
+	}
+
+	log.Printf("截止日期检查完成，新增 %d 条通知", len(created))
+	emailNotifications(created)
+}
+
+func deadlineKind(entity string, endDate time.Time, status string, today time.Time) string {
+	if isOverdue(dateOnly(endDate), status, today) {
+		return entity + "_overdue"
+	}
+	return entity + "_due_soon"
+}
+
+// 保存通知，已存在相同通知时返回false；多个实例同时检查也不会重复
+func recordNotification(n *Notification, entityID uint) bool {
+	if n.DedupKey == "" {
+		n.DedupKey = fmt.Sprintf("%s:%d:%d:%s", n.Type, entityID, n.MemberID, n.DueDate.Format("2006-01-02"))
+	}
+	result := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(n)
+	if result.Error != nil {
+		log.Printf("保存通知失败: %v", result.Error)
+		return false
+	}
+	return result.RowsAffected == 1
+}
+
+// 立即发送邮件：只发给选择即时通知的成员，每位成员合并为一封邮件
+func emailNotifications(notifications []Notification) {
+	if mailer == nil || len(notifications) == 0 {
+		return
+	}
+
+	byMember := map[uint][]Notification{}
+	var memberIDs []uint
+	for _, n := range notifications {
+		if _, ok := byMember[n.MemberID]; !ok {
+			memberIDs = append(memberIDs, n.MemberID)
+		}
+		byMember[n.MemberID] = append(byMember[n.MemberID], n)
+	}
+
+	var members []TeamMember
+	if err := DB.Where("id IN ? AND email_mode = ?", memberIDs, "instant").Find(&members).Error; err != nil {
+		log.Printf("查询通知收件人失败: %v", err)
+		return
+	}
+
+	for _, member := range members {
+		items := byMember[member.ID]
+		locale := mailer.localeFor(&member)
+		if sendNotificationEmail(&member, items, "notification", T(locale, "mail.notification_subject", len(items))) {
+			setNotificationsEmailed(items, time.Now())
+		}
+	}
+}
+
+// 每日汇总：将选择汇总方式的成员尚未发送的通知合并成一封邮件
+// 每个实例都会运行，发送前先认领通知，发送失败时再释放，避免多个实例重复发送
+func sendDailyDigests(now time.Time) {
+	if mailer == nil {
+		return
+	}
+
+	var members []TeamMember
+	if err := DB.Where("email_mode = ? AND is_active = ? AND email <> ?", "digest", true, "").
+		Where("id IN (SELECT member_id FROM notifications WHERE emailed_at IS NULL)").
+		Find(&members).Error; err != nil {
+		log.Printf("查询汇总邮件收件人失败: %v", err)
+		return
+	}
+
+	for _, member := range members {
+		items, err := claimDigestNotifications(member.ID, now)
+		if err != nil {
+			log.Printf("认领成员 %d 的通知失败: %v", member.ID, err)
+			continue
+		}
+		locale := mailer.localeFor(&member)
+		if !sendNotificationEmail(&member, items, "digest", T(locale, "mail.digest_subject", now.Format("2006-01-02"))) {
+			setNotificationsEmailed(items, time.Time{})
+		}
+	}
+}
+
+// 认领成员尚未发送的通知：UPDATE 按行加锁，并发的实例在等锁后重新检查 emailed_at，只有一个实例能认领到
+func claimDigestNotifications(memberID uint, now time.Time) ([]Notification, error) {
+	var items []Notification
+	if err := DB.Raw(`UPDATE notifications SET emailed_at = ?
+		WHERE member_id = ? AND emailed_at IS NULL
+		RETURNING *`, now, memberID).Scan(&items).Error; err != nil {
+		return nil, err
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+	return items, nil
+}
+
+// 设置通知的邮件发送时间，零值表示未发送
+func setNotificationsEmailed(items []Notification, emailedAt time.Time) {
+	if len(items) == 0 {
+		return
+	}
+	ids := make([]uint, len(items))
+	for i := range items {
+		ids[i] = items[i].ID
+	}
+	var value interface{} = emailedAt
+	if emailedAt.IsZero() {
+		value = nil
+	}
+	if err := DB.Model(&Notification{}).Where("id IN ?", ids).Update("emailed_at", value).Error; err != nil {
+		log.Printf("更新通知邮件状态失败: %v", err)
+	}
+}
+
+// 按模板发送通知邮件，返回是否发送成功
+func sendNotificationEmail(member *TeamMember, items []Notification, templateName, subject string) bool {
+	if member.Email == "" || !member.IsActive || len(items) == 0 {
+		return false
+	}
+
+	locale := mailer.localeFor(member)
+	messages := make([]string, len(items))
+	for i := range items {
+		messages[i] = notificationMessage(locale, &items[i])
+	}
+
+	body, err := renderMail(locale, templateName, mailData{Name: member.Name, Date: time.Now().Format("2006-01-02"), Items: messages})
+	if err != nil {
+		log.Printf("生成邮件内容失败: %v", err)
+		return false
+	}
+	if err := mailer.Send(member.Email, subject, body); err != nil {
+		log.Printf("发送邮件给 %s 失败: %v", member.Email, err)
+		return false
+	}
+	return true
+}
+
+// 任务指派、改派、改期和完成时通知相关成员
+// 指派和改期通知负责人，改派同时通知原负责人，完成通知项目经理
+func notifyTaskChange(action string, projectID uint, task, previous *Task) {
+	var created []Notification
+	add := func(memberID uint, kind string) {
+		n := Notification{MemberID: memberID, ProjectID: projectID, TaskID: &task.ID,
+			Type: kind, Subject: task.Name, DueDate: dateOnly(task.EndDate),
+			DedupKey: fmt.Sprintf("%s:%d:%d:v%d", kind, task.ID, memberID, task.Version)}
+		if recordNotification(&n, task.ID) {
+			created = append(created, n)
+		}
+	}
+
+	switch {
+	case action == "created":
+		if task.AssignedTo != 0 {
+			add(task.AssignedTo, "task_assigned")
+		}
+	case action == "updated" && previous != nil:
+		if task.AssignedTo != previous.AssignedTo {
+			if task.AssignedTo != 0 {
+				add(task.AssignedTo, "task_assigned")
+			}
+			if previous.AssignedTo != 0 {
+				add(previous.AssignedTo, "task_unassigned")
+			}
+		} else if task.AssignedTo != 0 && (!dateOnly(task.StartDate).Equal(dateOnly(previous.StartDate)) || !dateOnly(task.EndDate).Equal(dateOnly(previous.EndDate))) {
+			add(task.AssignedTo, "task_rescheduled")
+		}
+
+		if task.Status == "completed" && previous.Status != "completed" {
+			var managers []TeamMember
+			if err := DB.Where("project_id = ? AND role = ? AND is_active = ?", projectID, "pm", true).Find(&managers).Error; err != nil {
+				log.Printf("查询项目 %d 的项目经理失败: %v", projectID, err)
+			}
+			for _, manager := range managers {
+				add(manager.ID, "task_completed")
+			}
+		}
+	}
+
+	if len(created) > 0 {
+		go emailNotifications(created)
+	}
+}
+
+func notificationMessage(locale string, n *Notification) string {
+	return T(locale, "notify."+n.Type, n.Subject, n.DueDate.Format("2006-01-02"))
+}
+
+// 通知相关接口
+// 支持 member_id、project_id、unread=true 筛选，limit 默认50，最大200
+func getNotifications(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		respondError(c, badRequest("INVALID_PARAMETER"))
+		return
+	}
+
+	query := DB.Order("created_at DESC").Order("id DESC").Limit(limit)
+	if memberID := c.Query("member_id"); memberID != "" {
+		query = query.Where("member_id = ?", memberID)
+	}
+	if projectID := c.Query("project_id"); projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+	if c.Query("unread") == "true" {
+		query = query.Where("is_read = ?", false)
+	}
+
+	var notifications []Notification
+	if err := query.Find(&notifications).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	locale := getLocale(c)
+	for i := range notifications {
+		notifications[i].Message = notificationMessage(locale, &notifications[i])
+	}
+	c.JSON(http.StatusOK, notifications)
+}
+
+func markNotificationRead(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var notification Notification
+	if err := DB.First(&notification, id).Error; err != nil {
+		respondError(c, dbError(err, "NOTIFICATION_NOT_FOUND"))
+		return
+	}
+
+	if !notification.IsRead {
+		now := time.Now()
+		notification.IsRead, notification.ReadAt = true, &now
+		if err := DB.Model(&notification).Updates(map[string]interface{}{"is_read": true, "read_at": now}).Error; err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	notification.Message = notificationMessage(getLocale(c), &notification)
+	c.JSON(http.StatusOK, notification)
+}
+
+// 将成员的全部通知标记为已读
+func markAllNotificationsRead(c *gin.Context) {
+	memberID, err := strconv.ParseUint(c.Query("member_id"), 10, 32)
+	if err != nil || memberID == 0 {
+		respondError(c, badRequest("INVALID_PARAMETER"))
+		return
+	}
+
+	result := DB.Model(&Notification{}).Where("member_id = ? AND is_read = ?", memberID, false).
+		Updates(map[string]interface{}{"is_read": true, "read_at": time.Now()})
+	if result.Error != nil {
+		respondError(c, result.Error)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": result.RowsAffected})
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 阶段和任务统一按 order 排序，order 相同时按开始日期和ID排序
+func orderStages(db *gorm.DB) *gorm.DB {
+	return db.Order(`"order"`).Order("start_date").Order("id")
+}
+
+func orderTasks(db *gorm.DB) *gorm.DB {
+	return db.Order(`"order"`).Order("start_date").Order("id")
+}
+
+// 按顺序预加载项目的阶段及其任务
+func preloadStageTree(db *gorm.DB) *gorm.DB {
+	return db.Preload("Stages", orderStages).Preload("Stages.Tasks", orderTasks)
+}
+
+// 新建阶段或任务时排在同级的最后
+func nextOrder(db *gorm.DB, model interface{}, parentColumn string, parentID uint) (int, error) {
+	var maxOrder int
+	err := db.Model(model).Where(parentColumn+" = ?", parentID).Select(`COALESCE(MAX("order"), 0)`).Scan(&maxOrder).Error
+	return maxOrder + 1, err
+}
+
+// 调整项目内阶段的顺序
+func reorderStages(c *gin.Context) {
+	projectID, err := parseIDParam(c, "projectId")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var req struct {
+		StageIDs []uint `json:"stage_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&Project{}, projectID).Error; err != nil {
+			return dbError(err, "PROJECT_NOT_FOUND")
+		}
+		return rewriteOrder(tx, &Stage{}, "project_id", projectID, "stage_ids", req.StageIDs)
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var stages []Stage
+	if err := DB.Where("project_id = ?", projectID).Scopes(orderStages).Preload("Tasks", orderTasks).Find(&stages).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	publishReorder("stage", projectID, req.StageIDs)
+	c.JSON(http.StatusOK, stages)
+}
+
+// 调整阶段内任务的顺序
+func reorderTasks(c *gin.Context) {
+	stageID, err := parseIDParam(c, "stageId")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var req struct {
+		TaskIDs []uint `json:"task_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	var stage Stage
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id", "project_id").First(&stage, stageID).Error; err != nil {
+			return dbError(err, "STAGE_NOT_FOUND")
+		}
+		return rewriteOrder(tx, &Task{}, "stage_id", stageID, "task_ids", req.TaskIDs)
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var tasks []Task
+	if err := DB.Where("stage_id = ?", stageID).Scopes(orderTasks).Find(&tasks).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	publishReorder("task", stage.ProjectID, req.TaskIDs)
+	c.JSON(http.StatusOK, tasks)
+}
+
+// 按给定的ID顺序重写 order，ID列表必须恰好包含上级下的全部记录
+// 同级记录在事务内加锁，避免与并发的调整互相覆盖
+func rewriteOrder(tx *gorm.DB, model interface{}, parentColumn string, parentID uint, field string, ordered []uint) error {
+	var existing []uint
+	if err := tx.Model(model).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(parentColumn+" = ?", parentID).Pluck("id", &existing).Error; err != nil {
+		return err
+	}
+
+	var fe fieldErrors
+	remaining := make(map[uint]bool, len(existing))
+	for _, id := range existing {
+		remaining[id] = true
+	}
+	seen := make(map[uint]bool, len(ordered))
+	for _, id := range ordered {
+		switch {
+		case seen[id]:
+			fe.add(field, "DUPLICATE_ID", id)
+		case !remaining[id]:
+			fe.add(field, "REFERENCE_NOT_FOUND", id)
+		}
+		seen[id] = true
+		delete(remaining, id)
+	}
+	for _, id := range existing {
+		if remaining[id] {
+			fe.add(field, "MISSING_ID", id)
+		}
+	}
+	if err := fe.toError(); err != nil {
+		return err
+	}
+
+	for i, id := range ordered {
+		if err := tx.Model(model).Where("id = ?", id).
+			Updates(map[string]interface{}{"order": i + 1, "version": gorm.Expr("version + 1")}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
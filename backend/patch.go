@@ -0,0 +1,173 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 将补丁中的一个字段写入实体，值为 null 时清空该字段
+type patchSetter func(fe *fieldErrors, field string, value interface{})
+
+// 各实体允许修改的字段，未列出的字段（id、project_id、stage_id、created_at 等）不可修改
+func projectPatchFields(project *Project) map[string]patchSetter {
+	return map[string]patchSetter{
+		"name":        func(fe *fieldErrors, f string, v interface{}) { project.Name = fe.asString(f, v) },
+		"description": func(fe *fieldErrors, f string, v interface{}) { project.Description = fe.asString(f, v) },
+		"start_date":  func(fe *fieldErrors, f string, v interface{}) { project.StartDate = fe.asDate(f, v) },
+		"end_date":    func(fe *fieldErrors, f string, v interface{}) { project.EndDate = fe.asDate(f, v) },
+		"status":      func(fe *fieldErrors, f string, v interface{}) { project.Status = fe.asString(f, v) },
+	}
+}
+
+func stagePatchFields(stage *Stage) map[string]patchSetter {
+	return map[string]patchSetter{
+		"name":        func(fe *fieldErrors, f string, v interface{}) { stage.Name = fe.asString(f, v) },
+		"description": func(fe *fieldErrors, f string, v interface{}) { stage.Description = fe.asString(f, v) },
+		"start_date":  func(fe *fieldErrors, f string, v interface{}) { stage.StartDate = fe.asDate(f, v) },
+		"end_date":    func(fe *fieldErrors, f string, v interface{}) { stage.EndDate = fe.asDate(f, v) },
+		"status":      func(fe *fieldErrors, f string, v interface{}) { stage.Status = fe.asString(f, v) },
+		"progress":    func(fe *fieldErrors, f string, v interface{}) { stage.Progress = fe.asFloat(f, v) },
+	}
+}
+
+func taskPatchFields(task *Task) map[string]patchSetter {
+	return map[string]patchSetter{
+		"name":            func(fe *fieldErrors, f string, v interface{}) { task.Name = fe.asString(f, v) },
+		"description":     func(fe *fieldErrors, f string, v interface{}) { task.Description = fe.asString(f, v) },
+		"start_date":      func(fe *fieldErrors, f string, v interface{}) { task.StartDate = fe.asDate(f, v) },
+		"end_date":        func(fe *fieldErrors, f string, v interface{}) { task.EndDate = fe.asDate(f, v) },
+		"status":          func(fe *fieldErrors, f string, v interface{}) { task.Status = fe.asString(f, v) },
+		"priority":        func(fe *fieldErrors, f string, v interface{}) { task.Priority = fe.asString(f, v) },
+		"progress":        func(fe *fieldErrors, f string, v interface{}) { task.Progress = fe.asFloat(f, v) },
+		"assigned_to":     func(fe *fieldErrors, f string, v interface{}) { task.AssignedTo = fe.asID(f, v) },
+		"estimated_hours": func(fe *fieldErrors, f string, v interface{}) { task.EstimatedHours = fe.asFloat(f, v) },
+		"planned_cost":    func(fe *fieldErrors, f string, v interface{}) { task.PlannedCost = fe.asFloat(f, v) },
+		"actual_cost":     func(fe *fieldErrors, f string, v interface{}) { task.ActualCost = fe.asOptionalFloat(f, v) },
+	}
+}
+
+func memberPatchFields(member *TeamMember) map[string]patchSetter {
+	return map[string]patchSetter{
+		"name":       func(fe *fieldErrors, f string, v interface{}) { member.Name = fe.asString(f, v) },
+		"email":      func(fe *fieldErrors, f string, v interface{}) { member.Email = fe.asString(f, v) },
+		"role":       func(fe *fieldErrors, f string, v interface{}) { member.Role = fe.asString(f, v) },
+		"is_active":  func(fe *fieldErrors, f string, v interface{}) { member.IsActive = fe.asBool(f, v) },
+		"email_mode": func(fe *fieldErrors, f string, v interface{}) { member.EmailMode = fe.asString(f, v) },
+		"locale":     func(fe *fieldErrors, f string, v interface{}) { member.Locale = fe.asString(f, v) },
+		"avatar": func(fe *fieldErrors, f string, v interface{}) {
+			// 直接设置头像地址时不再使用上传的头像
+			if avatar := fe.asString(f, v); avatar != member.Avatar {
+				member.Avatar, member.AvatarKey = avatar, ""
+			}
+		},
+		"hourly_rate": func(fe *fieldErrors, f string, v interface{}) { member.HourlyRate = fe.asFloat(f, v) },
+	}
+}
+
+// PUT 忽略不可修改的字段以兼容提交整个对象的客户端，PATCH 遇到不可修改的字段时报错
+var (
+	updateProject    = mergePatchHandler("PROJECT_NOT_FOUND", projectPatchFields, validateProject, false)
+	patchProject     = mergePatchHandler("PROJECT_NOT_FOUND", projectPatchFields, validateProject, true)
+	updateStage      = mergePatchHandler("STAGE_NOT_FOUND", stagePatchFields, validateStage, false)
+	patchStage       = mergePatchHandler("STAGE_NOT_FOUND", stagePatchFields, validateStage, true)
+	updateTask       = mergePatchHandler("TASK_NOT_FOUND", taskPatchFields, validateTask, false)
+	patchTask        = mergePatchHandler("TASK_NOT_FOUND", taskPatchFields, validateTask, true)
+	updateTeamMember = mergePatchHandler("MEMBER_NOT_FOUND", memberPatchFields, validateTeamMember, false)
+	patchTeamMember  = mergePatchHandler("MEMBER_NOT_FOUND", memberPatchFields, validateTeamMember, true)
+)
+
+// 按 RFC 7396 合并补丁更新实体：只修改补丁中出现的字段，null 表示清空
+func mergePatchHandler[T any](notFoundCode string, fields func(*T) map[string]patchSetter, validate func(*gorm.DB, *T) error, strict bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := parseIDParam(c, "id")
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+
+		var entity T
+		if err := DB.First(&entity, id).Error; err != nil {
+			respondError(c, dbError(err, notFoundCode))
+			return
+		}
+
+		// 客户端必须携带读取时的版本号，防止覆盖他人的修改
+		record := any(&entity).(versioned)
+		if err := checkIfMatch(c, *record.versionRef(), entity); err != nil {
+			respondError(c, err)
+			return
+		}
+
+		patch, err := decodeMergePatch(c)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+
+		before := entity
+		if err := applyMergePatch(patch, fields(&entity), strict); err != nil {
+			respondError(c, err)
+			return
+		}
+
+		if err := validate(DB, &entity); err != nil {
+			respondError(c, err)
+			return
+		}
+
+		if err := saveVersioned(DB, record); err != nil {
+			respondError(c, err)
+			return
+		}
+
+		publishUpdate(&before, &entity)
+		setETag(c, *record.versionRef())
+		c.JSON(http.StatusOK, entity)
+	}
+}
+
+// 读取补丁，补丁必须是JSON对象
+func decodeMergePatch(c *gin.Context) (map[string]interface{}, error) {
+	var patch map[string]interface{}
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		return nil, bindError(err)
+	}
+	if patch == nil {
+		return nil, badRequest("PATCH_NOT_OBJECT")
+	}
+	return patch, nil
+}
+
+// 读取创建请求，按与补丁相同的规则写入新实体，fields 中可额外包含上级ID字段
+func bindCreateRequest(c *gin.Context, fields map[string]patchSetter) error {
+	body, err := decodeMergePatch(c)
+	if err != nil {
+		return err
+	}
+	return applyMergePatch(body, fields, false)
+}
+
+// 将补丁写入实体，所有字段错误一次性返回
+func applyMergePatch(patch map[string]interface{}, setters map[string]patchSetter, strict bool) error {
+	fields := make([]string, 0, len(patch))
+	for field := range patch {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var fe fieldErrors
+	for _, field := range fields {
+		setter, ok := setters[field]
+		if !ok {
+			if strict {
+				fe.add(field, "FIELD_NOT_PATCHABLE")
+			}
+			continue
+		}
+		setter(&fe, field, patch[field])
+	}
+	return fe.toError()
+}
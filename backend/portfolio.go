@@ -0,0 +1,259 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// 汇总表的列
+var portfolioColumns = []string{"project", "start_date", "end_date", "duration", "status", "progress", "task_count", "overdue"}
+
+// Excel工作表名称长度上限
+const maxSheetNameLength = 31
+
+// 项目组合导出的筛选条件
+type portfolioFilter struct {
+	Statuses []string
+	From     time.Time
+	To       time.Time
+}
+
+// 导出多个项目的汇总工作簿
+// layout=sheets（默认）每个项目一个时间线表，layout=combined 所有项目合并到一个时间线表
+func exportPortfolioToExcel(c *gin.Context) {
+	filter, code := parsePortfolioFilter(c)
+	if code != "" {
+		respondError(c, badRequest(code))
+		return
+	}
+
+	layout := c.DefaultQuery("layout", "sheets")
+	if layout != "sheets" && layout != "combined" {
+		respondError(c, badRequest("INVALID_LAYOUT"))
+		return
+	}
+
+	// 获取导出模板
+	template, err := findExportTemplate(c.Query("template"), getLocale(c))
+	if err != nil {
+		respondError(c, dbError(err, "EXPORT_TEMPLATE_NOT_FOUND"))
+		return
+	}
+
+	// 获取符合条件的项目
+	query := DB.Scopes(preloadStageTree).Preload("Stages.Tasks.Assignee").Order("start_date").Order("id")
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("end_date >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("start_date <= ?", filter.To)
+	}
+
+	var projects []Project
+	if err := query.Find(&projects).Error; err != nil {
+		log.Printf("查询项目列表失败: %v", err)
+		respondError(c, internalError("PROJECT_LOAD_FAILED", err))
+		return
+	}
+
+	// 创建Excel文件
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("关闭Excel文件失败: %v", err)
+		}
+	}()
+
+	if err := buildPortfolioWorkbook(f, projects, template, filter, layout); err != nil {
+		log.Printf("生成Excel文件失败: %v", err)
+		respondError(c, internalError("EXCEL_GENERATION_FAILED", err))
+		return
+	}
+
+	// 设置文件名
+	fileName := template.label("portfolio.file") + "_" + time.Now().Format("2006-01") + ".xlsx"
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+
+	// 写入响应
+	if err := f.Write(c.Writer); err != nil {
+		log.Printf("写入Excel文件失败: %v", err)
+		respondError(c, internalError("EXCEL_GENERATION_FAILED", err))
+		return
+	}
+}
+
+// 解析筛选参数：status 为逗号分隔的状态列表，from/to 为日期范围，出错时返回错误码
+func parsePortfolioFilter(c *gin.Context) (portfolioFilter, string) {
+	var filter portfolioFilter
+
+	for _, status := range strings.Split(c.Query("status"), ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	var err error
+	if from := c.Query("from"); from != "" {
+		if filter.From, err = time.Parse("2006-01-02", from); err != nil {
+			return filter, "INVALID_START_DATE"
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if filter.To, err = time.Parse("2006-01-02", to); err != nil {
+			return filter, "INVALID_END_DATE"
+		}
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return filter, "INVALID_DATE_RANGE"
+	}
+
+	return filter, ""
+}
+
+// 生成项目组合工作簿：汇总表加时间线表
+func buildPortfolioWorkbook(f *excelize.File, projects []Project, tpl *ExportTemplate, filter portfolioFilter, layout string) error {
+	b, err := newWorkbookBuilder(f, tpl)
+	if err != nil {
+		return err
+	}
+
+	usedNames := map[string]bool{}
+	summarySheet := uniqueSheetName(tpl.sheetName("portfolio"), usedNames)
+	if err := f.SetSheetName("Sheet1", summarySheet); err != nil {
+		return err
+	}
+	b.writePortfolioSummary(summarySheet, projects)
+
+	if layout == "combined" {
+		sheetName := uniqueSheetName(tpl.sheetName("combined"), usedNames)
+		if _, err := f.NewSheet(sheetName); err != nil {
+			return err
+		}
+		return b.writeCombinedTimeline(sheetName, projects, filter)
+	}
+
+	for _, project := range projects {
+		sheetName := uniqueSheetName(project.Name, usedNames)
+		if _, err := f.NewSheet(sheetName); err != nil {
+			return err
+		}
+		b.project = project
+		if err := b.writeTimelineSheet(sheetName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 汇总表：每个项目一行
+func (b *workbookBuilder) writePortfolioSummary(sheetName string, projects []Project) {
+	f, tpl := b.f, b.tpl
+	b.writeHeaders(sheetName, 1, 1, portfolioColumns)
+
+	today := dateOnly(time.Now())
+	row := 2
+	for _, project := range projects {
+		r := strconv.Itoa(row)
+		taskCount, overdue := 0, 0
+		for _, stage := range project.Stages {
+			for _, task := range stage.Tasks {
+				taskCount++
+				if isOverdue(task.EndDate, task.Status, today) {
+					overdue++
+				}
+			}
+		}
+
+		f.SetCellValue(sheetName, "A"+r, project.Name)
+		setScheduleCells(f, sheetName, "B", row, project.StartDate, project.EndDate, b.styles)
+		f.SetCellValue(sheetName, "E"+r, tpl.statusText(project.Status))
+		setProgressCell(f, sheetName, "F"+r, projectProgress(project), b.styles)
+		f.SetCellValue(sheetName, "G"+r, taskCount)
+		f.SetCellValue(sheetName, "H"+r, overdue)
+		row++
+	}
+}
+
+// 合并时间线：项目为顶层条，阶段和任务依次缩进
+func (b *workbookBuilder) writeCombinedTimeline(sheetName string, projects []Project, filter portfolioFilter) error {
+	var items []timelineItem
+	startDate, endDate := filter.From, filter.To
+	for _, project := range projects {
+		items = append(items, timelineItem{"🗂 " + project.Name, project.StartDate, project.EndDate, project.Status})
+		for _, stage := range project.Stages {
+			items = append(items, timelineItem{"  📁 " + stage.Name, stage.StartDate, stage.EndDate, stage.Status})
+			for _, task := range stage.Tasks {
+				items = append(items, timelineItem{"    📄 " + task.Name, task.StartDate, task.EndDate, task.Status})
+			}
+		}
+
+		// 未指定日期范围时覆盖所有项目
+		if filter.From.IsZero() && !project.StartDate.IsZero() && (startDate.IsZero() || project.StartDate.Before(startDate)) {
+			startDate = project.StartDate
+		}
+		if filter.To.IsZero() && project.EndDate.After(endDate) {
+			endDate = project.EndDate
+		}
+	}
+	return b.writeTimeline(sheetName, items, startDate, endDate)
+}
+
+// 项目进度取各阶段进度的平均值
+func projectProgress(project Project) float64 {
+	if len(project.Stages) == 0 {
+		if project.Status == "completed" {
+			return 100
+		}
+		return 0
+	}
+
+	total := 0.0
+	for _, stage := range project.Stages {
+		total += stage.Progress
+	}
+	return total / float64(len(project.Stages))
+}
+
+// 生成合法且不重复的工作表名称，Excel 的工作表名称不区分大小写，used 中保存小写的名称
+// 名称不能以单引号开头或结尾，截断后可能重新露出单引号，因此截断后再去除一次
+func uniqueSheetName(name string, used map[string]bool) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = trimSheetName(truncateRunes(trimSheetName(name), maxSheetNameLength))
+	if name == "" {
+		name = "Sheet"
+	}
+
+	candidate := name
+	for i := 2; used[strings.ToLower(candidate)]; i++ {
+		suffix := "(" + strconv.Itoa(i) + ")"
+		candidate = truncateRunes(name, maxSheetNameLength-len(suffix)) + suffix
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+func trimSheetName(name string) string {
+	return strings.Trim(name, "' \t\r\n")
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestUniqueSheetName(t *testing.T) {
+	longName := strings.Repeat("项目", 20)
+	tests := []struct {
+		name  string
+		input []string
+		want  []string
+	}{
+		{"不重复的名称保持不变", []string{"官网改版", "App"}, []string{"官网改版", "App"}},
+		{"替换非法字符", []string{"Q1/Q2: [计划]*?"}, []string{"Q1_Q2_ _计划___"}},
+		{"空名称", []string{"  ", ""}, []string{"Sheet", "Sheet(2)"}},
+		{"重名加序号", []string{"计划", "计划", "计划"}, []string{"计划", "计划(2)", "计划(3)"}},
+		{"不区分大小写", []string{"Plan", "plan", "PLAN"}, []string{"Plan", "plan(2)", "PLAN(3)"}},
+		{"与已有的汇总表重名", []string{"项目汇总"}, []string{"项目汇总(2)"}},
+		{"去除首尾单引号", []string{"'Alpha'", "''", " 'Beta' "}, []string{"Alpha", "Sheet", "Beta"}},
+		{"截断后去除结尾的单引号", []string{strings.Repeat("a", 30) + "'b"}, []string{strings.Repeat("a", 30)}},
+		{"超长名称截断", []string{longName, longName}, []string{strings.Repeat("项目", 15) + "项", strings.Repeat("项目", 14) + "(2)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used := map[string]bool{"项目汇总": true}
+			for i, input := range tt.input {
+				got := uniqueSheetName(input, used)
+				if got != tt.want[i] {
+					t.Errorf("uniqueSheetName(%q) = %q, want %q", input, got, tt.want[i])
+				}
+				if n := utf8.RuneCountInString(got); n > maxSheetNameLength {
+					t.Errorf("uniqueSheetName(%q) 长度为 %d，超过 %d", input, n, maxSheetNameLength)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildPortfolioWorkbook(t *testing.T) {
+	today := dateOnly(time.Now())
+	projects := []Project{
+		{ID: 1, Name: "'官网改版'", Status: "active", Stages: []Stage{{ID: 1, Name: "开发", Tasks: []Task{
+			{ID: 1, Name: "今天到期", EndDate: today, Status: "in_progress"},
+			{ID: 2, Name: "昨天到期", EndDate: today.AddDate(0, 0, -1), Status: "in_progress"},
+			{ID: 3, Name: "昨天完成", EndDate: today.AddDate(0, 0, -1), Status: "completed"},
+		}}}},
+		{ID: 2, Name: "官网改版", Status: "planning"},
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	tpl := GetDefaultExportTemplates()[0]
+	if err := buildPortfolioWorkbook(f, projects, tpl.withDefaults("zh-CN"), portfolioFilter{}, ""); err != nil {
+		t.Fatalf("buildPortfolioWorkbook() = %v", err)
+	}
+
+	sheets := f.GetSheetList()
+	if len(sheets) != 3 || sheets[1] != "官网改版" || sheets[2] != "官网改版(2)" {
+		t.Fatalf("工作表 = %v", sheets)
+	}
+	if got, _ := f.GetCellValue(sheets[0], "G2"); got != "3" {
+		t.Errorf("任务数 = %s, want 3", got)
+	}
+	if got, _ := f.GetCellValue(sheets[0], "H2"); got != "1" {
+		t.Errorf("逾期任务数 = %s, want 1（今天到期的任务不算逾期）", got)
+	}
+}
+
+func TestParsePortfolioFilter(t *testing.T) {
+	tests := []struct {
+		query    string
+		statuses []string
+		from, to string
+		errCode  string
+	}{
+		{query: ""},
+		{query: "status=active,%20planning,,", statuses: []string{"active", "planning"}},
+		{query: "from=2024-01-01&to=2024-06-30", from: "2024-01-01", to: "2024-06-30"},
+		{query: "from=2024-03-01&to=2024-03-01", from: "2024-03-01", to: "2024-03-01"},
+		{query: "from=2024-13-01", errCode: "INVALID_START_DATE"},
+		{query: "to=tomorrow", errCode: "INVALID_END_DATE"},
+		{query: "from=2024-06-30&to=2024-01-01", errCode: "INVALID_DATE_RANGE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/projects/export?"+tt.query, nil)
+
+			filter, errCode := parsePortfolioFilter(c)
+			if errCode != tt.errCode {
+				t.Fatalf("错误码 = %q, want %q", errCode, tt.errCode)
+			}
+			if errCode != "" {
+				return
+			}
+			if strings.Join(filter.Statuses, ",") != strings.Join(tt.statuses, ",") {
+				t.Errorf("Statuses = %v, want %v", filter.Statuses, tt.statuses)
+			}
+			if tt.from == "" && !filter.From.IsZero() || tt.from != "" && !filter.From.Equal(testDate(tt.from)) {
+				t.Errorf("From = %s, want %q", filter.From, tt.from)
+			}
+			if tt.to == "" && !filter.To.IsZero() || tt.to != "" && !filter.To.Equal(testDate(tt.to)) {
+				t.Errorf("To = %s, want %q", filter.To, tt.to)
+			}
+		})
+	}
+}
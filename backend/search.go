@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 搜索结果数量
+const (
+	searchDefaultLimit = 20
+	searchMaxLimit     = 100
+	searchSnippetRunes = 120
+)
+
+// 数据库是否安装了 pg_trgm 扩展，安装后按三元组相似度排序，并用三元组索引加速子串匹配
+var trigramAvailable bool
+
+// 可搜索的数据，Text 为参与搜索的文本表达式，与 initSearchIndexes 中的索引表达式保持一致
+type searchSource struct {
+	Type      string
+	Table     string
+	Title     string
+	Text      string
+	ProjectID string
+	Joins     string
+}
+
+var searchSources = []searchSource{
+	{
+		Type: "project", Table: "projects", Title: "projects.name",
+		Text:      "projects.name || ' ' || coalesce(projects.description, '')",
+		ProjectID: "projects.id",
+	},
+	{
+		Type: "stage", Table: "stages", Title: "stages.name",
+		Text:      "stages.name || ' ' || coalesce(stages.description, '')",
+		ProjectID: "stages.project_id",
+	},
+	{
+		Type: "task", Table: "tasks", Title: "tasks.name",
+		Text:      "tasks.name || ' ' || coalesce(tasks.description, '')",
+		ProjectID: "stages.project_id",
+		Joins:     "JOIN stages ON stages.id = tasks.stage_id",
+	},
+	{
+		Type: "member", Table: "team_members", Title: "team_members.name",
+		Text:      "team_members.name || ' ' || coalesce(team_members.email, '') || ' ' || team_members.role",
+		ProjectID: "team_members.project_id",
+	},
+	{
+		Type: "comment", Table: "comments", Title: "''",
+		Text:      "comments.body",
+		ProjectID: "comments.project_id",
+	},
+}
+
+// 创建全文检索和三元组索引，pg_trgm 不可用时只使用全文检索索引和普通子串匹配
+func initSearchIndexes() {
+	if err := DB.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		log.Printf("pg_trgm 扩展不可用，搜索不使用三元组相似度: %v", err)
+	} else {
+		trigramAvailable = true
+	}
+
+	for _, source := range searchSources {
+		// 索引表达式中不能带表名
+		expr := strings.ReplaceAll(source.Text, source.Table+".", "")
+		statements := []string{
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_search_fts ON %s USING gin (to_tsvector('simple', %s))", source.Table, source.Table, expr),
+		}
+		if trigramAvailable {
+			statements = append(statements, fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_search_trgm ON %s USING gin ((%s) gin_trgm_ops)", source.Table, source.Table, expr))
+		}
+		for _, statement := range statements {
+			if err := DB.Exec(statement).Error; err != nil {
+				log.Printf("创建搜索索引失败: %v", err)
+			}
+		}
+	}
+}
+
+type searchResult struct {
+	Type        string  `json:"type"`
+	ID          uint    `json:"id"`
+	ProjectID   uint    `json:"project_id"`
+	ProjectName string  `json:"project_name"`
+	Title       string  `json:"title"`
+	Highlight   string  `json:"highlight"` // 匹配处用 <mark> 标出的摘要，已做 HTML 转义
+	Rank        float64 `json:"rank"`
+	Content     string  `json:"-"`
+}
+
+// 搜索项目、阶段、任务、成员和评论
+// 使用 simple 配置的全文检索匹配整词，同时按子串匹配以支持不以空格分词的中文；所有关键词都要出现
+// 只搜索 member（成员邮箱）作为在职成员所在的项目，types 限定结果类型，project_id 限定项目
+func search(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	terms := strings.Fields(q)
+	if len(terms) == 0 {
+		respondError(c, badRequest("SEARCH_QUERY_REQUIRED"))
+		return
+	}
+	member := strings.TrimSpace(c.Query("member"))
+	if member == "" {
+		respondError(c, badRequest("SEARCH_MEMBER_REQUIRED"))
+		return
+	}
+
+	limit := searchDefaultLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			respondError(c, badRequest("INVALID_PARAMETER"))
+			return
+		}
+		if n > searchMaxLimit {
+			n = searchMaxLimit
+		}
+		limit = n
+	}
+
+	sources := searchSources
+	if value := c.Query("types"); value != "" {
+		sources = nil
+		for _, source := range searchSources {
+			if containsString(strings.Split(value, ","), source.Type) {
+				sources = append(sources, source)
+			}
+		}
+		if len(sources) == 0 {
+			respondError(c, badRequest("INVALID_PARAMETER"))
+			return
+		}
+	}
+
+	// 没有账号体系，按成员邮箱限定可见的项目
+	params := map[string]interface{}{"q": q, "member": member}
+	filters := []string{"%[1]s IN (SELECT project_id FROM team_members WHERE lower(email) = lower(@member) AND is_active)"}
+	if value := c.Query("project_id"); value != "" {
+		projectID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			respondError(c, badRequest("INVALID_PARAMETER"))
+			return
+		}
+		params["project_id"] = projectID
+		filters = append(filters, "%[1]s = @project_id")
+	}
+
+	var likes []string
+	for i, term := range terms {
+		name := "term" + strconv.Itoa(i)
+		params[name] = "%" + escapeLike(term) + "%"
+		likes = append(likes, "(%[2]s) ILIKE @"+name)
+	}
+
+	var queries []string
+	for _, source := range sources {
+		rank := "ts_rank(to_tsvector('simple', %[2]s), plainto_tsquery('simple', @q))"
+		if trigramAvailable {
+			rank += " + word_similarity(@q, %[2]s)"
+		}
+		rank += " + CASE WHEN lower(%[3]s) = lower(@q) THEN 1 ELSE 0 END"
+
+		where := "(to_tsvector('simple', %[2]s) @@ plainto_tsquery('simple', @q) OR (" + strings.Join(likes, " AND ") + "))"
+		for _, filter := range filters {
+			where += " AND " + filter
+		}
+
+		query := fmt.Sprintf("SELECT '%[4]s' AS type, %[5]s.id, %[1]s AS project_id, projects.name AS project_name, "+
+			"%[3]s AS title, %[2]s AS content, "+rank+" AS rank FROM %[5]s %[6]s "+
+			"JOIN projects ON projects.id = %[1]s WHERE "+where,
+			source.ProjectID, source.Text, source.Title, source.Type, source.Table, source.Joins)
+		queries = append(queries, query)
+	}
+	params["limit"] = limit
+
+	var results []searchResult
+	sql := strings.Join(queries, " UNION ALL ") + " ORDER BY rank DESC, type, id LIMIT @limit"
+	if err := DB.Raw(sql, params).Scan(&results).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	for i := range results {
+		results[i].Highlight = highlight(results[i].Content, terms, searchSnippetRunes)
+	}
+	if results == nil {
+		results = []searchResult{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":   q,
+		"results": results,
+	})
+}
+
+// 转义 LIKE 中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// 截取第一个匹配附近最多 maxRunes 个字符，匹配的关键词用 <mark> 标出，其余文本做 HTML 转义
+func highlight(text string, terms []string, maxRunes int) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// 找出所有匹配区间
+	type span struct{ start, end int }
+	var spans []span
+	for _, term := range terms {
+		needle := []rune(strings.Map(unicode.ToLower, term))
+		if len(needle) == 0 {
+			continue
+		}
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if string(lower[i:i+len(needle)]) == string(needle) {
+				spans = append(spans, span{i, i + len(needle)})
+				i += len(needle) - 1
+			}
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	// 以第一个匹配为中心截取
+	start, end := 0, len(runes)
+	if len(runes) > maxRunes {
+		if len(spans) > 0 {
+			start = spans[0].start - maxRunes/3
+			if start < 0 {
+				start = 0
+			}
+		}
+		end = start + maxRunes
+		if end > len(runes) {
+			end, start = len(runes), len(runes)-maxRunes
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, s := range spans {
+		if s.start < pos || s.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:s.start])))
+		b.WriteString("<mark>" + html.EscapeString(string(runes[s.start:s.end])) + "</mark>")
+		pos = s.end
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
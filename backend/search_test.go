@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		terms    []string
+		maxRunes int
+		want     string
+	}{
+		{"单个关键词", "需求调研 访谈核心用户", []string{"调研"}, 120, "需求<mark>调研</mark> 访谈核心用户"},
+		{"不区分大小写并保留原文", "Deploy the API gateway", []string{"api"}, 120, "Deploy the <mark>API</mark> gateway"},
+		{"多个关键词", "接口联调和接口测试", []string{"接口", "测试"}, 120, "<mark>接口</mark>联调和<mark>接口</mark><mark>测试</mark>"},
+		{"转义HTML", "<b>加粗</b> & 调研", []string{"调研"}, 120, "&lt;b&gt;加粗&lt;/b&gt; &amp; <mark>调研</mark>"},
+		{"关键词本身含HTML", "a<b>c", []string{"<b>"}, 120, "a<mark>&lt;b&gt;</mark>c"},
+		{"没有匹配", "需求调研", []string{"测试"}, 120, "需求调研"},
+		{"重叠的匹配只标出靠前的", "abcd", []string{"bcd", "abc"}, 120, "<mark>abc</mark>d"},
+		{"截取第一个匹配附近", "0123456789调研0123456789", []string{"调研"}, 9, "…789<mark>调研</mark>0123…"},
+		{"靠近结尾时向前截取", "0123456789调研", []string{"调研"}, 6, "…6789<mark>调研</mark>"},
+		{"没有匹配时从头截取", "0123456789", []string{"x"}, 4, "0123…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlight(tt.text, tt.terms, tt.maxRunes); got != tt.want {
+				t.Errorf("highlight(%q, %q, %d) = %q, want %q", tt.text, tt.terms, tt.maxRunes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	if got, want := escapeLike(`100%_a\b`), `100\%\_a\\b`; got != want {
+		t.Errorf("escapeLike() = %q, want %q", got, want)
+	}
+	if got := escapeLike("调研"); got != "调研" {
+		t.Errorf("escapeLike(调研) = %q", got)
+	}
+}
+
+func TestSearchSourcesAreIndexable(t *testing.T) {
+	for _, source := range searchSources {
+		expr := strings.ReplaceAll(source.Text, source.Table+".", "")
+		if strings.Contains(expr, ".") {
+			t.Errorf("%s 的搜索文本引用了其他表，无法建立表达式索引: %s", source.Type, source.Text)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 任务统计汇总
+type taskStats struct {
+	Total       int64   `json:"total"`
+	Completed   int64   `json:"completed"`
+	Overdue     int64   `json:"overdue"`
+	DueThisWeek int64   `json:"due_this_week"`
+	AvgProgress float64 `json:"avg_progress"` // 任务平均进度
+	Completion  float64 `json:"completion"`   // 已完成任务占比，百分比
+}
+
+// 按角色或成员的任务统计
+type memberStats struct {
+	MemberID    uint    `json:"member_id,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	ProjectID   uint    `json:"project_id,omitempty"`
+	Role        string  `json:"role"`
+	Members     int64   `json:"members,omitempty"`
+	Tasks       int64   `json:"tasks"`
+	Completed   int64   `json:"completed"`
+	Overdue     int64   `json:"overdue"`
+	AvgProgress float64 `json:"avg_progress"`
+}
+
+type statsReport struct {
+	ProjectID  uint             `json:"project_id,omitempty"`
+	Projects   map[string]int64 `json:"projects,omitempty"` // 全局统计时按状态统计项目数
+	Tasks      taskStats        `json:"tasks"`
+	ByStatus   map[string]int64 `json:"by_status"`
+	ByPriority map[string]int64 `json:"by_priority"`
+	ByRole     []memberStats    `json:"by_role"`
+	ByMember   []memberStats    `json:"by_member"`
+}
+
+// 单个项目的统计
+func getProjectStats(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var project Project
+	if err := DB.Select("id").First(&project, id).Error; err != nil {
+		respondError(c, dbError(err, "PROJECT_NOT_FOUND"))
+		return
+	}
+
+	report, err := computeStats(&project.ID, time.Now())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	report.ProjectID = project.ID
+	c.JSON(http.StatusOK, report)
+}
+
+// 所有项目的统计
+func getStats(c *gin.Context) {
+	report, err := computeStats(nil, time.Now())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := DB.Model(&Project{}).Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	report.Projects = map[string]int64{}
+	for _, status := range projectStatuses {
+		report.Projects[status] = 0
+	}
+	for _, row := range rows {
+		report.Projects[row.Status] = row.Count
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// 用 SQL 聚合计算统计数据，projectID 为空时统计所有项目
+// 逾期为结束日期早于今天且未完成，本周到期为今天到本周日之间到期且未完成
+func computeStats(projectID *uint, now time.Time) (*statsReport, error) {
+	today := dateOnly(now)
+	weekEnd := weekStart(today).AddDate(0, 0, 7)
+
+	tasks := func() *gorm.DB {
+		db := DB.Model(&Task{}).Joins("JOIN stages ON stages.id = tasks.stage_id")
+		if projectID != nil {
+			db = db.Where("stages.project_id = ?", *projectID)
+		}
+		return db
+	}
+	// 成员统计：没有任务的成员也列出
+	members := func() *gorm.DB {
+		db := DB.Model(&TeamMember{}).Joins("LEFT JOIN tasks ON tasks.assigned_to = team_members.id")
+		if projectID != nil {
+			db = db.Where("team_members.project_id = ?", *projectID)
+		}
+		return db
+	}
+
+	const (
+		completedExpr = "COALESCE(SUM(CASE WHEN tasks.status = 'completed' THEN 1 ELSE 0 END), 0)"
+		overdueExpr   = "COALESCE(SUM(CASE WHEN tasks.status <> 'completed' AND tasks.end_date < @today THEN 1 ELSE 0 END), 0)"
+		progressExpr  = "COALESCE(AVG(tasks.progress), 0)"
+	)
+	params := map[string]interface{}{"today": today, "week_end": weekEnd}
+
+	report := &statsReport{
+		ByStatus:   map[string]int64{},
+		ByPriority: map[string]int64{},
+		ByRole:     []memberStats{},
+		ByMember:   []memberStats{},
+	}
+
+	if err := tasks().Select("COUNT(*) AS total, "+completedExpr+" AS completed, "+overdueExpr+" AS overdue, "+
+		"COALESCE(SUM(CASE WHEN tasks.status <> 'completed' AND tasks.end_date >= @today AND tasks.end_date < @week_end THEN 1 ELSE 0 END), 0) AS due_this_week, "+
+		progressExpr+" AS avg_progress", params).Scan(&report.Tasks).Error; err != nil {
+		return nil, err
+	}
+	if report.Tasks.Total > 0 {
+		report.Tasks.Completion = float64(report.Tasks.Completed) * 100 / float64(report.Tasks.Total)
+	}
+
+	for _, group := range []struct {
+		column string
+		values []string
+		counts map[string]int64
+	}{
+		{"status", taskStatuses, report.ByStatus},
+		{"priority", taskPriorities, report.ByPriority},
+	} {
+		var rows []struct {
+			Value string
+			Count int64
+		}
+		if err := tasks().Select("tasks." + group.column + " AS value, COUNT(*) AS count").Group("tasks." + group.column).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, value := range group.values {
+			group.counts[value] = 0
+		}
+		for _, row := range rows {
+			group.counts[row.Value] = row.Count
+		}
+	}
+
+	if err := members().Select("team_members.role, COUNT(DISTINCT team_members.id) AS members, COUNT(tasks.id) AS tasks, "+
+		completedExpr+" AS completed, "+overdueExpr+" AS overdue, "+progressExpr+" AS avg_progress", params).
+		Group("team_members.role").Order("team_members.role").Scan(&report.ByRole).Error; err != nil {
+		return nil, err
+	}
+
+	if err := members().Select("team_members.id AS member_id, team_members.name, team_members.project_id, team_members.role, COUNT(tasks.id) AS tasks, "+
+		completedExpr+" AS completed, "+overdueExpr+" AS overdue, "+progressExpr+" AS avg_progress", params).
+		Group("team_members.id").Order("team_members.project_id").Order("team_members.id").Scan(&report.ByMember).Error; err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
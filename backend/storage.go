@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 附件存储，key 为存储内的相对路径
+type fileStorage interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// 文件不存在时返回的错误
+var errObjectNotFound = errors.New("object not found")
+
+var Storage fileStorage
+
+// 根据配置初始化附件存储：local（本地目录）或 s3（S3 兼容的对象存储，如 MinIO）
+func InitStorage(config *Config) {
+	attachmentMaxSize = int64(config.AttachmentMaxSizeMB) << 20
+	attachmentAllowedTypes = nil
+	for _, t := range strings.Split(config.AttachmentTypes, ",") {
+		if t = strings.TrimSpace(strings.ToLower(t)); t != "" {
+			attachmentAllowedTypes = append(attachmentAllowedTypes, t)
+		}
+	}
+
+	switch config.StorageBackend {
+	case "s3":
+		s, err := newS3Storage(config)
+		if err != nil {
+			log.Fatal("Failed to initialize S3 storage:", err)
+		}
+		Storage = s
+		log.Printf("附件存储使用 S3: %s/%s", config.S3Endpoint, config.S3Bucket)
+	default:
+		Storage = &localStorage{root: config.StorageDir}
+		log.Printf("附件存储使用本地目录: %s", config.StorageDir)
+	}
+}
+
+// 本地文件系统存储
+type localStorage struct {
+	root string
+}
+
+// 将 key 转换为存储目录内的路径，拒绝跳出目录的 key
+func (s *localStorage) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if filepath.IsAbs(clean) || clean == "." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) || clean == ".." {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(s.root, clean), nil
+}
+
+func (s *localStorage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// 先写临时文件再改名，避免读到写了一半的文件
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *localStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errObjectNotFound
+	}
+	return f, err
+}
+
+func (s *localStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// S3 兼容存储，使用 AWS Signature V4 签名，请求体不参与签名（UNSIGNED-PAYLOAD）
+type s3Storage struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	pathStyle bool // MinIO 等自建服务通常使用路径形式 endpoint/bucket/key
+	client    *http.Client
+}
+
+func newS3Storage(config *Config) (*s3Storage, error) {
+	endpoint, err := url.Parse(config.S3Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid S3_ENDPOINT %q", config.S3Endpoint)
+	}
+	if config.S3Bucket == "" {
+		return nil, errors.New("S3_BUCKET is required")
+	}
+	return &s3Storage{
+		endpoint:  endpoint,
+		bucket:    config.S3Bucket,
+		region:    config.S3Region,
+		accessKey: config.S3AccessKey,
+		secretKey: config.S3SecretKey,
+		pathStyle: config.S3PathStyle,
+		client:    &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+func (s *s3Storage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	resp, err := s.do(ctx, http.MethodPut, key, body, size, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return s3Error(resp)
+}
+
+func (s *s3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := s3Error(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *s3Storage) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, 0, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return s3Error(resp)
+}
+
+// 对象的请求地址
+func (s *s3Storage) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	basePath := strings.TrimSuffix(u.Path, "/")
+	if s.pathStyle {
+		u.Path = basePath + "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = basePath + "/" + key
+	}
+	u.RawPath = s3EscapePath(u.Path)
+	return &u
+}
+
+func (s *s3Storage) do(ctx context.Context, method, key string, body io.Reader, size int64, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, time.Now().UTC())
+	return s.client.Do(req)
+}
+
+// 按 Signature V4 为请求添加 Authorization 头
+func (s *s3Storage) sign(req *http.Request, now time.Time) {
+	const payloadHash = "UNSIGNED-PAYLOAD"
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		headers["content-type"] = contentType
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex(canonicalRequest)}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// 非2xx响应转换为错误，404 转换为 errObjectNotFound
+func s3Error(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return errObjectNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3 request failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
+
+// S3 要求路径中除 A-Z a-z 0-9 - _ . ~ / 以外的字节都按 %XX 编码
+func s3EscapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		ch := path[i]
+		if ('A' <= ch && ch <= 'Z') || ('a' <= ch && ch <= 'z') || ('0' <= ch && ch <= '9') ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' || ch == '/' {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
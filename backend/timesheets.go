@@ -0,0 +1,285 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 单条工时和任务预估工时的上限
+const (
+	maxEntryHours     = 24
+	maxEstimatedHours = 10000
+)
+
+func timeEntryPatchFields(entry *TimeEntry) map[string]patchSetter {
+	return map[string]patchSetter{
+		"date":  func(fe *fieldErrors, f string, v interface{}) { entry.Date = fe.asDate(f, v) },
+		"hours": func(fe *fieldErrors, f string, v interface{}) { entry.Hours = fe.asFloat(f, v) },
+		"note":  func(fe *fieldErrors, f string, v interface{}) { entry.Note = fe.asString(f, v) },
+	}
+}
+
+// 校验工时记录：成员必须属于任务所在项目，同一成员一天的工时合计不超过24小时
+// 校验通过后根据任务填写 ProjectID
+func validateTimeEntry(entry *TimeEntry) error {
+	var fe fieldErrors
+	if entry.Date.IsZero() {
+		fe.add("date", "FIELD_REQUIRED")
+	}
+	entry.Date = dateOnly(entry.Date)
+	if entry.Hours <= 0 || entry.Hours > maxEntryHours {
+		fe.add("hours", "OUT_OF_RANGE", 0, maxEntryHours)
+	}
+
+	var task Task
+	taskFound, err := fe.reference(DB, "task_id", entry.TaskID, &task)
+	if err != nil {
+		return err
+	}
+	var member TeamMember
+	memberFound, err := fe.reference(DB, "member_id", entry.MemberID, &member)
+	if err != nil {
+		return err
+	}
+
+	var projectID uint
+	if taskFound {
+		if err := DB.Model(&Stage{}).Where("id = ?", task.StageID).Pluck("project_id", &projectID).Error; err != nil {
+			return err
+		}
+		if memberFound && member.ProjectID != projectID {
+			fe.add("member_id", "MEMBER_NOT_IN_PROJECT")
+		}
+	}
+
+	if memberFound && !entry.Date.IsZero() && entry.Hours > 0 {
+		var logged float64
+		if err := DB.Model(&TimeEntry{}).Where("member_id = ? AND date = ? AND id <> ?", entry.MemberID, entry.Date, entry.ID).
+			Select("COALESCE(SUM(hours), 0)").Scan(&logged).Error; err != nil {
+			return err
+		}
+		if logged+entry.Hours > maxEntryHours {
+			fe.add("hours", "DAILY_HOURS_EXCEEDED", maxEntryHours, logged)
+		}
+	}
+
+	if err := fe.toError(); err != nil {
+		return err
+	}
+	entry.ProjectID = projectID
+	return nil
+}
+
+// 工时相关接口
+// 任务的工时记录及预估与实际工时对比
+func getTaskTimeEntries(c *gin.Context) {
+	taskID, err := parseIDParam(c, "taskId")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var task Task
+	if err := DB.First(&task, taskID).Error; err != nil {
+		respondError(c, dbError(err, "TASK_NOT_FOUND"))
+		return
+	}
+
+	var entries []TimeEntry
+	if err := DB.Where("task_id = ?", taskID).Preload("Member").Order("date").Order("id").Find(&entries).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var actual float64
+	for _, entry := range entries {
+		actual += entry.Hours
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"task_id":         task.ID,
+		"estimated_hours": task.EstimatedHours,
+		"actual_hours":    actual,
+		"remaining_hours": task.EstimatedHours - actual,
+		"entries":         entries,
+	})
+}
+
+func createTimeEntry(c *gin.Context) {
+	var entry TimeEntry
+	fields := timeEntryPatchFields(&entry)
+	fields["task_id"] = func(fe *fieldErrors, f string, v interface{}) { entry.TaskID = fe.asID(f, v) }
+	fields["member_id"] = func(fe *fieldErrors, f string, v interface{}) { entry.MemberID = fe.asID(f, v) }
+	if err := bindCreateRequest(c, fields); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := validateTimeEntry(&entry); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := DB.Create(&entry).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	publishChange("created", &entry)
+	c.JSON(http.StatusCreated, entry)
+}
+
+func updateTimeEntry(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var entry TimeEntry
+	if err := DB.First(&entry, id).Error; err != nil {
+		respondError(c, dbError(err, "TIME_ENTRY_NOT_FOUND"))
+		return
+	}
+	before := entry
+
+	patch, err := decodeMergePatch(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := applyMergePatch(patch, timeEntryPatchFields(&entry), false); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := validateTimeEntry(&entry); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := DB.Save(&entry).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	publishUpdate(&before, &entry)
+	c.JSON(http.StatusOK, entry)
+}
+
+func deleteTimeEntry(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var entry TimeEntry
+	if err := DB.First(&entry, id).Error; err != nil {
+		respondError(c, dbError(err, "TIME_ENTRY_NOT_FOUND"))
+		return
+	}
+
+	if err := DB.Delete(&entry).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	publishChange("deleted", &entry)
+	respondMessage(c, http.StatusOK, "TIME_ENTRY_DELETED")
+}
+
+// 周工时表中的一行：一个任务在一周七天的工时
+type timesheetRow struct {
+	TaskID    uint       `json:"task_id"`
+	TaskName  string     `json:"task_name"`
+	StageName string     `json:"stage_name"`
+	Hours     [7]float64 `json:"hours"` // 周一到周日
+	Total     float64    `json:"total"`
+}
+
+// 成员的周工时表，week 为该周任意一天，默认本周
+func getMemberTimesheet(c *gin.Context) {
+	memberID, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	day := time.Now()
+	if week := c.Query("week"); week != "" {
+		if day, err = parseDate(week); err != nil {
+			respondError(c, badRequest("INVALID_PARAMETER"))
+			return
+		}
+	}
+	weekStart := weekStart(day)
+	weekEnd := weekStart.AddDate(0, 0, 6)
+
+	var member TeamMember
+	if err := DB.First(&member, memberID).Error; err != nil {
+		respondError(c, dbError(err, "MEMBER_NOT_FOUND"))
+		return
+	}
+
+	var entries []TimeEntry
+	if err := DB.Where("member_id = ? AND date BETWEEN ? AND ?", memberID, weekStart, weekEnd).
+		Preload("Task.Stage").Order("date").Order("id").Find(&entries).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	rows := []*timesheetRow{}
+	byTask := map[uint]*timesheetRow{}
+	var daily [7]float64
+	var total float64
+	for _, entry := range entries {
+		row, ok := byTask[entry.TaskID]
+		if !ok {
+			row = &timesheetRow{TaskID: entry.TaskID, TaskName: entry.Task.Name, StageName: entry.Task.Stage.Name}
+			byTask[entry.TaskID] = row
+			rows = append(rows, row)
+		}
+		day := int(dateOnly(entry.Date).Sub(weekStart).Hours() / 24)
+		row.Hours[day] += entry.Hours
+		row.Total += entry.Hours
+		daily[day] += entry.Hours
+		total += entry.Hours
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"member_id":  member.ID,
+		"member":     member.Name,
+		"week_start": weekStart.Format("2006-01-02"),
+		"week_end":   weekEnd.Format("2006-01-02"),
+		"rows":       rows,
+		"daily":      daily,
+		"total":      total,
+	})
+}
+
+// 所在周的周一
+func weekStart(day time.Time) time.Time {
+	day = dateOnly(day)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// 统计项目内每个任务的实际工时
+func sumTaskHours(projectID uint) (map[uint]float64, error) {
+	var rows []struct {
+		TaskID uint
+		Hours  float64
+	}
+	if err := DB.Model(&TimeEntry{}).Select("task_id, SUM(hours) AS hours").
+		Where("project_id = ?", projectID).Group("task_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	hours := make(map[uint]float64, len(rows))
+	for _, row := range rows {
+		hours[row.TaskID] = row.Hours
+	}
+	return hours, nil
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 各字段允许的取值
+var (
+	projectStatuses = []string{"active", "completed", "paused"}
+	stageStatuses   = []string{"pending", "in_progress", "completed"}
+	taskStatuses    = []string{"pending", "in_progress", "completed"}
+	taskPriorities  = []string{"low", "medium", "high", "urgent"}
+	emailModes      = []string{"instant", "digest", "off"}
+)
+
+// 收集字段错误，校验结束后一次性返回
+type fieldErrors []FieldError
+
+func (fe *fieldErrors) add(field, code string, args ...interface{}) {
+	*fe = append(*fe, FieldError{Field: field, Code: code, Args: args})
+}
+
+// 没有错误时返回nil
+func (fe fieldErrors) toError() error {
+	if len(fe) == 0 {
+		return nil
+	}
+	return &APIError{Status: http.StatusUnprocessableEntity, Code: "VALIDATION_FAILED", Fields: fe}
+}
+
+// 以下方法从JSON解码后的值中读取指定类型，null 返回零值，类型不符时记录错误并返回零值
+func (fe *fieldErrors) asString(field string, value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	s, ok := value.(string)
+	if !ok {
+		fe.add(field, "INVALID_TYPE", "string")
+	}
+	return s
+}
+
+func (fe *fieldErrors) asFloat(field string, value interface{}) float64 {
+	if value == nil {
+		return 0
+	}
+	f, ok := value.(float64)
+	if !ok {
+		fe.add(field, "INVALID_TYPE", "number")
+	}
+	return f
+}
+
+// null 表示未设置
+func (fe *fieldErrors) asOptionalFloat(field string, value interface{}) *float64 {
+	if value == nil {
+		return nil
+	}
+	f := fe.asFloat(field, value)
+	return &f
+}
+
+func (fe *fieldErrors) asBool(field string, value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	b, ok := value.(bool)
+	if !ok {
+		fe.add(field, "INVALID_TYPE", "boolean")
+	}
+	return b
+}
+
+func (fe *fieldErrors) asID(field string, value interface{}) uint {
+	if value == nil {
+		return 0
+	}
+	f, ok := value.(float64)
+	if !ok || f < 0 || f != float64(uint(f)) {
+		fe.add(field, "INVALID_TYPE", "id")
+		return 0
+	}
+	return uint(f)
+}
+
+func (fe *fieldErrors) asStrings(field string, value interface{}) []string {
+	if value == nil {
+		return nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		fe.add(field, "INVALID_TYPE", "string[]")
+		return nil
+	}
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			fe.add(field, "INVALID_TYPE", "string[]")
+			return nil
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+func (fe *fieldErrors) asDate(field string, value interface{}) time.Time {
+	if value == nil {
+		return time.Time{}
+	}
+	s, ok := value.(string)
+	if !ok {
+		fe.add(field, "INVALID_TYPE", "date")
+		return time.Time{}
+	}
+	date, err := parseDate(s)
+	if err != nil {
+		fe.add(field, "INVALID_DATE")
+	}
+	return date
+}
+
+func (fe *fieldErrors) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		fe.add(field, "FIELD_REQUIRED")
+	}
+}
+
+func (fe *fieldErrors) oneOf(field, value string, allowed []string) {
+	if !containsString(allowed, value) {
+		fe.add(field, "INVALID_ENUM", strings.Join(allowed, ", "))
+	}
+}
+
+func (fe *fieldErrors) progress(field string, value float64) {
+	if value < 0 || value > 100 {
+		fe.add(field, "OUT_OF_RANGE", 0, 100)
+	}
+}
+
+// 校验开始和结束日期：均不能为空且开始不晚于结束
+func (fe *fieldErrors) dateRange(start, end time.Time) bool {
+	if start.IsZero() {
+		fe.add("start_date", "FIELD_REQUIRED")
+	}
+	if end.IsZero() {
+		fe.add("end_date", "FIELD_REQUIRED")
+	}
+	if start.IsZero() || end.IsZero() {
+		return false
+	}
+	if dateOnly(start).After(dateOnly(end)) {
+		fe.add("end_date", "END_BEFORE_START")
+		return false
+	}
+	return true
+}
+
+// 校验日期范围是否落在上级的日期范围内
+func (fe *fieldErrors) within(start, end, parentStart, parentEnd time.Time, code string) {
+	if !parentStart.IsZero() && dateOnly(start).Before(dateOnly(parentStart)) {
+		fe.add("start_date", code, parentStart.Format("2006-01-02"), parentEnd.Format("2006-01-02"))
+	}
+	if !parentEnd.IsZero() && dateOnly(end).After(dateOnly(parentEnd)) {
+		fe.add("end_date", code, parentStart.Format("2006-01-02"), parentEnd.Format("2006-01-02"))
+	}
+}
+
+// 校验已有下级的日期范围是否仍然落在新的日期范围内
+func (fe *fieldErrors) containsChildren(start, end time.Time, query *gorm.DB, code string) error {
+	var span struct {
+		MinStart *time.Time
+		MaxEnd   *time.Time
+	}
+	if err := query.Select("MIN(start_date) AS min_start, MAX(end_date) AS max_end").Scan(&span).Error; err != nil {
+		return err
+	}
+	if span.MinStart != nil && dateOnly(*span.MinStart).Before(dateOnly(start)) {
+		fe.add("start_date", code, span.MinStart.Format("2006-01-02"))
+	}
+	if span.MaxEnd != nil && dateOnly(*span.MaxEnd).After(dateOnly(end)) {
+		fe.add("end_date", code, span.MaxEnd.Format("2006-01-02"))
+	}
+	return nil
+}
+
+// 查询上级记录，不存在时记录字段错误，其他数据库错误直接返回
+func (fe *fieldErrors) reference(db *gorm.DB, field string, id uint, dest interface{}) (bool, error) {
+	if id == 0 {
+		fe.add(field, "FIELD_REQUIRED")
+		return false, nil
+	}
+	if err := db.First(dest, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			fe.add(field, "REFERENCE_NOT_FOUND", id)
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// 校验项目
+func validateProject(db *gorm.DB, project *Project) error {
+	var fe fieldErrors
+	fe.required("name", project.Name)
+	fe.oneOf("status", project.Status, projectStatuses)
+	if fe.dateRange(project.StartDate, project.EndDate) && project.ID != 0 {
+		if err := fe.containsChildren(project.StartDate, project.EndDate, db.Model(&Stage{}).Where("project_id = ?", project.ID), "STAGES_OUTSIDE_RANGE"); err != nil {
+			return err
+		}
+	}
+	return fe.toError()
+}
+
+// 校验阶段，阶段日期必须在项目日期范围内，已有任务必须在阶段日期范围内
+func validateStage(db *gorm.DB, stage *Stage) error {
+	var fe fieldErrors
+	fe.required("name", stage.Name)
+	fe.oneOf("status", stage.Status, stageStatuses)
+	fe.progress("progress", stage.Progress)
+	datesOK := fe.dateRange(stage.StartDate, stage.EndDate)
+
+	var project Project
+	found, err := fe.reference(db, "project_id", stage.ProjectID, &project)
+	if err != nil {
+		return err
+	}
+	if found && datesOK {
+		fe.within(stage.StartDate, stage.EndDate, project.StartDate, project.EndDate, "OUTSIDE_PROJECT_RANGE")
+	}
+	if datesOK && stage.ID != 0 {
+		if err := fe.containsChildren(stage.StartDate, stage.EndDate, db.Model(&Task{}).Where("stage_id = ?", stage.ID), "TASKS_OUTSIDE_RANGE"); err != nil {
+			return err
+		}
+	}
+	return fe.toError()
+}
+
+// 校验任务，任务日期必须在阶段日期范围内，负责人必须是同一项目的成员
+func validateTask(db *gorm.DB, task *Task) error {
+	var fe fieldErrors
+	fe.required("name", task.Name)
+	fe.oneOf("status", task.Status, taskStatuses)
+	fe.oneOf("priority", task.Priority, taskPriorities)
+	fe.progress("progress", task.Progress)
+	if task.EstimatedHours < 0 || task.EstimatedHours > maxEstimatedHours {
+		fe.add("estimated_hours", "OUT_OF_RANGE", 0, maxEstimatedHours)
+	}
+	if task.PlannedCost < 0 || task.PlannedCost > maxCost {
+		fe.add("planned_cost", "OUT_OF_RANGE", 0, maxCost)
+	}
+	if task.ActualCost != nil && (*task.ActualCost < 0 || *task.ActualCost > maxCost) {
+		fe.add("actual_cost", "OUT_OF_RANGE", 0, maxCost)
+	}
+	datesOK := fe.dateRange(task.StartDate, task.EndDate)
+
+	var stage Stage
+	found, err := fe.reference(db, "stage_id", task.StageID, &stage)
+	if err != nil {
+		return err
+	}
+	if found && datesOK {
+		fe.within(task.StartDate, task.EndDate, stage.StartDate, stage.EndDate, "OUTSIDE_STAGE_RANGE")
+	}
+
+	if task.AssignedTo != 0 {
+		var member TeamMember
+		memberFound, err := fe.reference(db, "assigned_to", task.AssignedTo, &member)
+		if err != nil {
+			return err
+		}
+		if memberFound && found && member.ProjectID != stage.ProjectID {
+			fe.add("assigned_to", "ASSIGNEE_NOT_IN_PROJECT")
+		}
+	}
+	return fe.toError()
+}
+
+// 邮箱只能是地址本身，不接受 "Name <a@b.com>" 这类带显示名称或前后空格的写法
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// 校验团队成员，角色必须是已定义的角色
+func validateTeamMember(db *gorm.DB, member *TeamMember) error {
+	var fe fieldErrors
+	fe.required("name", member.Name)
+	if member.Email != "" && !validEmail(member.Email) {
+		fe.add("email", "INVALID_EMAIL")
+	}
+
+	if member.HourlyRate < 0 || member.HourlyRate > maxHourlyRate {
+		fe.add("hourly_rate", "OUT_OF_RANGE", 0, maxHourlyRate)
+	}
+
+	fe.oneOf("email_mode", member.EmailMode, emailModes)
+	if member.Locale != "" {
+		fe.oneOf("locale", member.Locale, supportedLocales)
+	}
+
+	if _, err := fe.reference(db, "project_id", member.ProjectID, &Project{}); err != nil {
+		return err
+	}
+
+	if member.Role == "" {
+		fe.add("role", "FIELD_REQUIRED")
+	} else {
+		var roleNames []string
+		if err := db.Model(&Role{}).Order("id").Pluck("name", &roleNames).Error; err != nil {
+			return err
+		}
+		if !containsString(roleNames, member.Role) {
+			fe.add("role", "INVALID_ENUM", strings.Join(roleNames, ", "))
+		}
+	}
+	return fe.toError()
+}
+
+// 解析日期，支持 2006-01-02 和 RFC3339 两种格式
+func parseDate(s string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", s); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// 只保留日期部分，按UTC零点表示
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestValidEmail(t *testing.T) {
+	tests := map[string]bool{
+		"alice@example.com":           true,
+		"bob.smith+gantt@example.com": true,
+		"Alice <alice@example.com>":   false,
+		"<alice@example.com>":         false,
+		" alice@example.com":          false,
+		"alice@example.com ":          false,
+		"alice":                       false,
+		"alice@":                      false,
+		"alice@example.com, bob@x.cn": false,
+	}
+	for email, want := range tests {
+		if got := validEmail(email); got != want {
+			t.Errorf("validEmail(%q) = %v, want %v", email, got, want)
+		}
+	}
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 任务查询的返回数量和 due_within_days 的上限
+const (
+	taskQueryDefaultLimit = 100
+	taskQueryMaxLimit     = 500
+	maxDueWithinDays      = 3650
+)
+
+// 任务筛选条件，所有条件同时满足；同一条件的多个值满足其一即可
+type TaskFilter struct {
+	ProjectIDs    []uint   `json:"project_ids,omitempty"`
+	StageIDs      []uint   `json:"stage_ids,omitempty"`
+	Statuses      []string `json:"statuses,omitempty"`
+	Priorities    []string `json:"priorities,omitempty"`
+	AssigneeIDs   []uint   `json:"assignee_ids,omitempty"`
+	Roles         []string `json:"roles,omitempty"`           // 负责人角色
+	DueFrom       string   `json:"due_from,omitempty"`        // 结束日期不早于，YYYY-MM-DD
+	DueTo         string   `json:"due_to,omitempty"`          // 结束日期不晚于，YYYY-MM-DD
+	DueWithinDays *int     `json:"due_within_days,omitempty"` // 未完成且在今天起若干天内到期（含已逾期），保存后随日期滚动
+	Overdue       bool     `json:"overdue,omitempty"`         // 只返回已逾期的任务
+	Text          string   `json:"text,omitempty"`            // 名称或描述包含的文字
+}
+
+// 按筛选条件过滤任务，db 需已连接 stages 表
+func applyTaskFilter(db *gorm.DB, filter TaskFilter, today time.Time) *gorm.DB {
+	if len(filter.ProjectIDs) > 0 {
+		db = db.Where("stages.project_id IN ?", filter.ProjectIDs)
+	}
+	if len(filter.StageIDs) > 0 {
+		db = db.Where("tasks.stage_id IN ?", filter.StageIDs)
+	}
+	if len(filter.Statuses) > 0 {
+		db = db.Where("tasks.status IN ?", filter.Statuses)
+	}
+	if len(filter.Priorities) > 0 {
+		db = db.Where("tasks.priority IN ?", filter.Priorities)
+	}
+	if len(filter.AssigneeIDs) > 0 {
+		db = db.Where("tasks.assigned_to IN ?", filter.AssigneeIDs)
+	}
+	if len(filter.Roles) > 0 {
+		db = db.Where("tasks.assigned_to IN (SELECT id FROM team_members WHERE role IN ?)", filter.Roles)
+	}
+	if date, err := parseDate(filter.DueFrom); err == nil {
+		db = db.Where("tasks.end_date >= ?", dateOnly(date))
+	}
+	if date, err := parseDate(filter.DueTo); err == nil {
+		db = db.Where("tasks.end_date < ?", dateOnly(date).AddDate(0, 0, 1))
+	}
+	if filter.DueWithinDays != nil {
+		db = db.Where("tasks.status <> ? AND tasks.end_date < ?", "completed", today.AddDate(0, 0, *filter.DueWithinDays+1))
+	}
+	if filter.Overdue {
+		db = db.Where("tasks.status <> ? AND tasks.end_date < ?", "completed", today)
+	}
+	if text := strings.TrimSpace(filter.Text); text != "" {
+		like := "%" + escapeLike(text) + "%"
+		db = db.Where("(tasks.name ILIKE ? OR tasks.description ILIKE ?)", like, like)
+	}
+	return db
+}
+
+func validateTaskFilter(fe *fieldErrors, field string, filter TaskFilter) {
+	for _, status := range filter.Statuses {
+		fe.oneOf(field+".statuses", status, taskStatuses)
+	}
+	for _, priority := range filter.Priorities {
+		fe.oneOf(field+".priorities", priority, taskPriorities)
+	}
+	for name, value := range map[string]string{"due_from": filter.DueFrom, "due_to": filter.DueTo} {
+		if value == "" {
+			continue
+		}
+		if _, err := parseDate(value); err != nil {
+			fe.add(field+"."+name, "INVALID_DATE")
+		}
+	}
+	if filter.DueWithinDays != nil && (*filter.DueWithinDays < 0 || *filter.DueWithinDays > maxDueWithinDays) {
+		fe.add(field+".due_within_days", "OUT_OF_RANGE", 0, maxDueWithinDays)
+	}
+}
+
+// 从查询参数读取筛选条件，view 为已保存视图的ID，查询参数中出现的条件覆盖视图中的同名条件
+func parseTaskFilter(c *gin.Context) (TaskFilter, error) {
+	var filter TaskFilter
+	if value := c.Query("view"); value != "" {
+		viewID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || viewID == 0 {
+			return filter, badRequest("INVALID_ID", "view")
+		}
+		view, err := findSavedView(uint(viewID))
+		if err != nil {
+			return filter, err
+		}
+		filter = view.Filter
+	}
+
+	list := func(name string) []string {
+		var values []string
+		for _, value := range strings.Split(c.Query(name), ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		return values
+	}
+	ids := func(name string, dest *[]uint) error {
+		values := list(name)
+		if len(values) == 0 {
+			return nil
+		}
+		*dest = nil
+		for _, value := range values {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return badRequest("INVALID_PARAMETER")
+			}
+			*dest = append(*dest, uint(id))
+		}
+		return nil
+	}
+
+	if err := ids("project_id", &filter.ProjectIDs); err != nil {
+		return filter, err
+	}
+	if err := ids("stage_id", &filter.StageIDs); err != nil {
+		return filter, err
+	}
+	if err := ids("assignee", &filter.AssigneeIDs); err != nil {
+		return filter, err
+	}
+	if values := list("status"); len(values) > 0 {
+		filter.Statuses = values
+	}
+	if values := list("priority"); len(values) > 0 {
+		filter.Priorities = values
+	}
+	if values := list("role"); len(values) > 0 {
+		filter.Roles = values
+	}
+	if value := c.Query("due_from"); value != "" {
+		filter.DueFrom = value
+	}
+	if value := c.Query("due_to"); value != "" {
+		filter.DueTo = value
+	}
+	if value := c.Query("due_within"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, badRequest("INVALID_PARAMETER")
+		}
+		filter.DueWithinDays = &days
+	}
+	if value := c.Query("overdue"); value != "" {
+		filter.Overdue = value == "true" || value == "1"
+	}
+	if value := c.Query("q"); value != "" {
+		filter.Text = value
+	}
+
+	var fe fieldErrors
+	validateTaskFilter(&fe, "filter", filter)
+	return filter, fe.toError()
+}
+
+// 任务查询，支持跨项目筛选
+func queryTasks(c *gin.Context) {
+	filter, err := parseTaskFilter(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(taskQueryDefaultLimit)))
+	if err != nil || limit <= 0 || limit > taskQueryMaxLimit {
+		respondError(c, badRequest("INVALID_PARAMETER"))
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		respondError(c, badRequest("INVALID_PARAMETER"))
+		return
+	}
+
+	query := applyTaskFilter(DB.Model(&Task{}).Joins("JOIN stages ON stages.id = tasks.stage_id"), filter, dateOnly(time.Now()))
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var tasks []Task
+	if err := query.Preload("Stage").Preload("Assignee").
+		Order("tasks.end_date").Order("tasks.id").Limit(limit).Offset(offset).Find(&tasks).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	if tasks == nil {
+		tasks = []Task{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"filter": filter,
+		"total":  total,
+		"tasks":  tasks,
+	})
+}
+
+// 给出 ?view= 或 ?filter=true 时按视图及筛选参数过滤已加载的项目任务，否则不做处理，
+// 以免 status、q 等参数改变原有接口的结果；阶段总是保留，没有匹配任务时任务列表为空
+func filterProjectTasks(c *gin.Context, project *Project) error {
+	if c.Query("view") == "" && c.Query("filter") != "true" {
+		return nil
+	}
+	filter, err := parseTaskFilter(c)
+	if err != nil {
+		return err
+	}
+	if isEmptyTaskFilter(filter) {
+		return nil
+	}
+
+	var ids []uint
+	if err := applyTaskFilter(DB.Model(&Task{}).Joins("JOIN stages ON stages.id = tasks.stage_id"), filter, dateOnly(time.Now())).
+		Where("stages.project_id = ?", project.ID).Pluck("tasks.id", &ids).Error; err != nil {
+		return err
+	}
+	matched := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		matched[id] = true
+	}
+
+	for i := range project.Stages {
+		stage := &project.Stages[i]
+		tasks := stage.Tasks[:0]
+		for _, task := range stage.Tasks {
+			if matched[task.ID] {
+				tasks = append(tasks, task)
+			}
+		}
+		stage.Tasks = tasks
+	}
+	return nil
+}
+
+// 删除项目时从视图的筛选条件中去掉该项目及其阶段、成员的ID，
+// 某个条件因此变为空（即不再限制）时删除整个视图，避免视图扩大到其他项目
+func removeProjectFromViews(tx *gorm.DB, projectID uint) error {
+	var stageIDs, memberIDs []uint
+	if err := tx.Model(&Stage{}).Where("project_id = ?", projectID).Pluck("id", &stageIDs).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&TeamMember{}).Where("project_id = ?", projectID).Pluck("id", &memberIDs).Error; err != nil {
+		return err
+	}
+
+	var views []SavedView
+	if err := tx.Find(&views).Error; err != nil {
+		return err
+	}
+	for i := range views {
+		view := &views[i]
+		changed, emptied := false, false
+		for _, list := range []struct {
+			ids     *[]uint
+			removed []uint
+		}{
+			{&view.Filter.ProjectIDs, []uint{projectID}},
+			{&view.Filter.StageIDs, stageIDs},
+			{&view.Filter.AssigneeIDs, memberIDs},
+		} {
+			var kept []uint
+			for _, id := range *list.ids {
+				if !containsID(list.removed, id) {
+					kept = append(kept, id)
+				}
+			}
+			if len(kept) != len(*list.ids) {
+				changed, emptied = true, emptied || len(kept) == 0
+				*list.ids = kept
+			}
+		}
+
+		switch {
+		case emptied:
+			if err := tx.Delete(view).Error; err != nil {
+				return err
+			}
+		case changed:
+			if err := tx.Save(view).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmptyTaskFilter(filter TaskFilter) bool {
+	data, _ := json.Marshal(filter)
+	return string(data) == "{}"
+}
+
+// 已保存视图相关接口
+func savedViewPatchFields(view *SavedView) map[string]patchSetter {
+	return map[string]patchSetter{
+		"name":   func(fe *fieldErrors, f string, v interface{}) { view.Name = fe.asString(f, v) },
+		"filter": func(fe *fieldErrors, f string, v interface{}) { view.Filter = decodeTaskFilter(fe, f, v) },
+	}
+}
+
+// 将请求中的 filter 对象转换为筛选条件，不允许未知字段
+func decodeTaskFilter(fe *fieldErrors, field string, value interface{}) TaskFilter {
+	var filter TaskFilter
+	if value == nil {
+		return filter
+	}
+	if _, ok := value.(map[string]interface{}); !ok {
+		fe.add(field, "INVALID_TYPE", "object")
+		return filter
+	}
+	data, _ := json.Marshal(value)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&filter); err != nil {
+		fe.add(field, "INVALID_TYPE", "filter")
+	}
+	return filter
+}
+
+// 校验视图：名称和所有者必填，同一所有者的视图名称不能重复
+func validateSavedView(view *SavedView) error {
+	var fe fieldErrors
+	fe.required("name", view.Name)
+	fe.required("owner", view.Owner)
+	validateTaskFilter(&fe, "filter", view.Filter)
+
+	if view.Name != "" && view.Owner != "" {
+		var count int64
+		if err := DB.Model(&SavedView{}).Where("owner = ? AND name = ? AND id <> ?", view.Owner, view.Name, view.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			fe.add("name", "VIEW_NAME_TAKEN")
+		}
+	}
+	return fe.toError()
+}
+
+func findSavedView(id uint) (*SavedView, error) {
+	var view SavedView
+	if err := DB.First(&view, id).Error; err != nil {
+		return nil, dbError(err, "VIEW_NOT_FOUND")
+	}
+	return &view, nil
+}
+
+// 视图列表，owner 指定时只返回该所有者的视图
+func getSavedViews(c *gin.Context) {
+	query := DB.Order("owner").Order("name")
+	if owner := c.Query("owner"); owner != "" {
+		query = query.Where("owner = ?", owner)
+	}
+
+	var views []SavedView
+	if err := query.Find(&views).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+func createSavedView(c *gin.Context) {
+	var view SavedView
+	fields := savedViewPatchFields(&view)
+	fields["owner"] = func(fe *fieldErrors, f string, v interface{}) { view.Owner = fe.asString(f, v) }
+	if err := bindCreateRequest(c, fields); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := validateSavedView(&view); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := DB.Create(&view).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, view)
+}
+
+func updateSavedView(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	view, err := findSavedView(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	patch, err := decodeMergePatch(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := applyMergePatch(patch, savedViewPatchFields(view), false); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := validateSavedView(view); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := DB.Save(view).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+func deleteSavedView(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	result := DB.Delete(&SavedView{}, id)
+	if result.Error != nil {
+		respondError(c, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondError(c, notFound("VIEW_NOT_FOUND"))
+		return
+	}
+	respondMessage(c, http.StatusOK, "VIEW_DELETED")
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 可订阅的事件类型，过滤条件还可以使用 "*" 和 "task.*" 这样的通配符
+// 删除项目时其Webhook一并删除，因此不提供 project.deleted
+var webhookEventTypes = []string{
+	"project.updated",
+	"stage.created", "stage.updated", "stage.reordered", "stage.completed", "stage.deadline_slipped",
+	"task.created", "task.updated", "task.deleted", "task.reordered", "task.completed", "task.deadline_slipped",
+	"member.created", "member.updated",
+	"comment.created", "comment.updated", "comment.deleted",
+	"attachment.created", "attachment.deleted",
+	"time_entry.created", "time_entry.updated", "time_entry.deleted",
+}
+
+// 投递参数
+const (
+	maxWebhookAttempts = 6                // 最多尝试次数（含首次）
+	webhookRetryBase   = 30 * time.Second // 首次重试间隔，之后每次翻倍
+	webhookRetryMax    = time.Hour        // 重试间隔上限
+	webhookBatchSize   = 20
+	webhookTimeout     = 10 * time.Second
+	// 认领投递后其他实例等待的时间，一批投递依次发送，租约须长于整批都超时的耗时
+	webhookLease         = webhookBatchSize*webhookTimeout + time.Minute
+	webhookPollInterval  = 10 * time.Second
+	webhookResponseLimit = 1024 // 记录的响应体长度
+)
+
+var (
+	webhookClient = &http.Client{Timeout: webhookTimeout}
+	webhookWake   = make(chan struct{}, 1)
+)
+
+// 启动投递协程
+func InitWebhooks() {
+	go runWebhookWorker()
+}
+
+func wakeWebhookWorker() {
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// 为匹配事件的Webhook生成投递记录，由发布事件的实例调用，多实例部署时不会重复投递
+func enqueueWebhooks(event Event) {
+	if event.ProjectID == 0 {
+		return
+	}
+
+	var hooks []Webhook
+	if err := DB.Where("project_id = ? AND is_active = ?", event.ProjectID, true).Find(&hooks).Error; err != nil {
+		log.Printf("查询项目 %d 的Webhook失败: %v", event.ProjectID, err)
+		return
+	}
+
+	var payload []byte
+	queued := false
+	for _, hook := range hooks {
+		if !matchEventFilter(hook.Events, event.Type) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(event); err != nil {
+				log.Printf("序列化Webhook负载失败: %v", err)
+				return
+			}
+		}
+		delivery := WebhookDelivery{
+			WebhookID:     hook.ID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        "pending",
+			NextAttemptAt: time.Now(),
+		}
+		if err := DB.Create(&delivery).Error; err != nil {
+			log.Printf("创建Webhook %d 投递记录失败: %v", hook.ID, err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		wakeWebhookWorker()
+	}
+}
+
+func matchEventFilter(filter []string, eventType string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, pattern := range filter {
+		if pattern == "*" || pattern == eventType {
+			return true
+		}
+		if strings.HasSuffix(pattern, ".*") && strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func runWebhookWorker() {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		processDueDeliveries()
+		select {
+		case <-ticker.C:
+		case <-webhookWake:
+		}
+	}
+}
+
+// 处理所有到期的投递
+func processDueDeliveries() {
+	for {
+		deliveries, err := claimDueDeliveries()
+		if err != nil {
+			log.Printf("获取待投递的Webhook失败: %v", err)
+			return
+		}
+		if len(deliveries) == 0 {
+			return
+		}
+		for i := range deliveries {
+			deliverWebhook(&deliveries[i])
+		}
+	}
+}
+
+// 认领一批到期的投递：推后下次尝试时间作为租约，SKIP LOCKED 保证多个实例不会同时处理同一条记录
+func claimDueDeliveries() ([]WebhookDelivery, error) {
+	now := time.Now()
+	var deliveries []WebhookDelivery
+	err := DB.Raw(`UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= ?
+			ORDER BY next_attempt_at LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		) RETURNING *`, now.Add(webhookLease), now, webhookBatchSize).Scan(&deliveries).Error
+	return deliveries, err
+}
+
+// 发送一次投递并记录结果，失败时按指数退避安排重试
+func deliverWebhook(delivery *WebhookDelivery) {
+	updates := map[string]interface{}{"attempts": delivery.Attempts + 1}
+
+	var hook Webhook
+	err := DB.First(&hook, delivery.WebhookID).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !hook.IsActive):
+		updates["status"] = "failed"
+		updates["error"] = "webhook deleted or disabled"
+	case err != nil:
+		log.Printf("读取Webhook %d 失败: %v", delivery.WebhookID, err)
+		return
+	default:
+		code, body, sendErr := sendWebhook(&hook, delivery)
+		recordDeliveryResult(updates, delivery.Attempts+1, code, body, sendErr, time.Now())
+	}
+
+	if err := DB.Model(delivery).Updates(updates).Error; err != nil {
+		log.Printf("更新Webhook投递记录 %d 失败: %v", delivery.ID, err)
+	}
+}
+
+// 记录一次发送的结果：2xx 为成功，达到最多尝试次数为失败，否则安排下次重试
+func recordDeliveryResult(updates map[string]interface{}, attempts, code int, body string, sendErr error, now time.Time) {
+	updates["response_code"] = code
+	updates["response_body"] = body
+	updates["error"] = ""
+	if sendErr != nil {
+		updates["error"] = sendErr.Error()
+	}
+
+	switch {
+	case sendErr == nil && code >= 200 && code < 300:
+		updates["status"] = "succeeded"
+		updates["delivered_at"] = &now
+	case attempts >= maxWebhookAttempts:
+		updates["status"] = "failed"
+	default:
+		updates["next_attempt_at"] = now.Add(webhookRetryDelay(attempts))
+	}
+}
+
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > webhookRetryMax {
+		delay = webhookRetryMax
+	}
+	return delay
+}
+
+func sendWebhook(hook *Webhook, delivery *WebhookDelivery) (int, string, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gantt-excel-webhook")
+	req.Header.Set("X-Gantt-Event", delivery.EventType)
+	req.Header.Set("X-Gantt-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Gantt-Timestamp", timestamp)
+	req.Header.Set("X-Gantt-Signature", signWebhook(hook.Secret, timestamp, delivery.Payload))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	return resp.StatusCode, string(body), nil
+}
+
+// 签名为 HMAC-SHA256(secret, timestamp + "." + body) 的十六进制值
+func signWebhook(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func webhookPatchFields(hook *Webhook) map[string]patchSetter {
+	return map[string]patchSetter{
+		"url":       func(fe *fieldErrors, f string, v interface{}) { hook.URL = fe.asString(f, v) },
+		"secret":    func(fe *fieldErrors, f string, v interface{}) { hook.Secret = fe.asString(f, v) },
+		"events":    func(fe *fieldErrors, f string, v interface{}) { hook.Events = fe.asStrings(f, v) },
+		"is_active": func(fe *fieldErrors, f string, v interface{}) { hook.IsActive = fe.asBool(f, v) },
+	}
+}
+
+// 校验Webhook：URL必须是http(s)地址，事件过滤必须是已知事件或通配符
+func validateWebhook(hook *Webhook) error {
+	var fe fieldErrors
+	if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fe.add("url", "INVALID_URL")
+	}
+	if hook.Secret == "" {
+		fe.add("secret", "FIELD_REQUIRED")
+	}
+	for _, pattern := range hook.Events {
+		entity := strings.TrimSuffix(pattern, ".*")
+		if pattern != "*" && !containsString(webhookEventTypes, pattern) &&
+			!(entity != pattern && containsString([]string{"project", "stage", "task", "member", "comment", "attachment", "time_entry"}, entity)) {
+			fe.add("events", "UNKNOWN_EVENT", pattern)
+		}
+	}
+	if _, err := fe.reference(DB, "project_id", hook.ProjectID, &Project{}); err != nil {
+		return err
+	}
+	return fe.toError()
+}
+
+// Webhook相关接口
+func getWebhooks(c *gin.Context) {
+	projectID, err := parseIDParam(c, "projectId")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var hooks []Webhook
+	if err := DB.Where("project_id = ?", projectID).Order("id").Find(&hooks).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	c.JSON(http.StatusOK, hooks)
+}
+
+func createWebhook(c *gin.Context) {
+	hook := Webhook{IsActive: true}
+	fields := webhookPatchFields(&hook)
+	fields["project_id"] = func(fe *fieldErrors, f string, v interface{}) { hook.ProjectID = fe.asID(f, v) }
+	if err := bindCreateRequest(c, fields); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// 未指定密钥时自动生成
+	if hook.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			respondError(c, internalError("WEBHOOK_SECRET_FAILED", err))
+			return
+		}
+		hook.Secret = secret
+	}
+
+	if err := validateWebhook(&hook); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := DB.Create(&hook).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, hook)
+}
+
+func updateWebhook(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var hook Webhook
+	if err := DB.First(&hook, id).Error; err != nil {
+		respondError(c, dbError(err, "WEBHOOK_NOT_FOUND"))
+		return
+	}
+
+	patch, err := decodeMergePatch(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := applyMergePatch(patch, webhookPatchFields(&hook), false); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := validateWebhook(&hook); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := DB.Save(&hook).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	hook.Secret = ""
+	c.JSON(http.StatusOK, hook)
+}
+
+func deleteWebhook(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&Webhook{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return notFound("WEBHOOK_NOT_FOUND")
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	respondMessage(c, http.StatusOK, "WEBHOOK_DELETED")
+}
+
+// 投递记录，按时间倒序，limit 默认50，最大200
+func getWebhookDeliveries(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		respondError(c, badRequest("INVALID_PARAMETER"))
+		return
+	}
+
+	if err := DB.Select("id").First(&Webhook{}, id).Error; err != nil {
+		respondError(c, dbError(err, "WEBHOOK_NOT_FOUND"))
+		return
+	}
+
+	query := DB.Where("webhook_id = ?", id)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []WebhookDelivery
+	if err := query.Order("id DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// 重新投递：以原负载生成一条新的投递记录
+func redeliverWebhook(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var original WebhookDelivery
+	if err := DB.First(&original, id).Error; err != nil {
+		respondError(c, dbError(err, "WEBHOOK_DELIVERY_NOT_FOUND"))
+		return
+	}
+
+	if err := DB.Select("id").First(&Webhook{}, original.WebhookID).Error; err != nil {
+		respondError(c, dbError(err, "WEBHOOK_NOT_FOUND"))
+		return
+	}
+
+	delivery := WebhookDelivery{
+		WebhookID:     original.WebhookID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        "pending",
+		NextAttemptAt: time.Now(),
+	}
+	if err := DB.Create(&delivery).Error; err != nil {
+		respondError(c, err)
+		return
+	}
+
+	wakeWebhookWorker()
+	c.JSON(http.StatusAccepted, delivery)
+}
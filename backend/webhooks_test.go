@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignWebhook(t *testing.T) {
+	got := signWebhook("topsecret", "1700000000", `{"type":"task.created"}`)
+	want := "sha256=badc3b4b483ee8cd1337ad7ee4b8f2b899c16d3ae54581a25e7ec99600dd1c37"
+	if got != want {
+		t.Errorf("signWebhook() = %s, want %s", got, want)
+	}
+	if other := signWebhook("othersecret", "1700000000", `{"type":"task.created"}`); other == got {
+		t.Error("不同的密钥生成了相同的签名")
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("webhookRetryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestMatchEventFilter(t *testing.T) {
+	tests := []struct {
+		filter    []string
+		eventType string
+		want      bool
+	}{
+		{nil, "task.created", true},
+		{[]string{"*"}, "stage.updated", true},
+		{[]string{"task.created"}, "task.created", true},
+		{[]string{"task.created"}, "task.updated", false},
+		{[]string{"task.*"}, "task.deadline_slipped", true},
+		{[]string{"task.*"}, "stage.created", false},
+		{[]string{"task.*"}, "time_entry.created", false},
+		{[]string{"stage.completed", "comment.*"}, "comment.deleted", true},
+	}
+	for _, tt := range tests {
+		if got := matchEventFilter(tt.filter, tt.eventType); got != tt.want {
+			t.Errorf("matchEventFilter(%v, %q) = %v, want %v", tt.filter, tt.eventType, got, tt.want)
+		}
+	}
+}
+
+func TestDeliverWebhookSucceeded(t *testing.T) {
+	hook := &Webhook{ID: 1, Secret: "topsecret"}
+	delivery := &WebhookDelivery{ID: 7, WebhookID: 1, EventType: "task.completed", Payload: `{"type":"task.completed"}`, Attempts: 0}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || string(body) != delivery.Payload {
+			t.Errorf("收到 %s %s", r.Method, body)
+		}
+		if got := r.Header.Get("X-Gantt-Event"); got != "task.completed" {
+			t.Errorf("X-Gantt-Event = %q", got)
+		}
+		if got := r.Header.Get("X-Gantt-Delivery"); got != "7" {
+			t.Errorf("X-Gantt-Delivery = %q", got)
+		}
+		want := signWebhook(hook.Secret, r.Header.Get("X-Gantt-Timestamp"), string(body))
+		if got := r.Header.Get("X-Gantt-Signature"); got != want {
+			t.Errorf("X-Gantt-Signature = %q, want %q", got, want)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	hook.URL = server.URL
+
+	code, body, err := sendWebhook(hook, delivery)
+	if err != nil || code != http.StatusOK || body != "ok" {
+		t.Fatalf("sendWebhook() = %d, %q, %v", code, body, err)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{}
+	recordDeliveryResult(updates, delivery.Attempts+1, code, body, err, now)
+	if updates["status"] != "succeeded" || updates["delivered_at"] == nil {
+		t.Errorf("成功投递的记录 = %v", updates)
+	}
+	if _, ok := updates["next_attempt_at"]; ok {
+		t.Error("成功投递不应安排重试")
+	}
+}
+
+func TestDeliverWebhookFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	hook := &Webhook{ID: 1, URL: server.URL, Secret: "topsecret"}
+	delivery := &WebhookDelivery{ID: 8, WebhookID: 1, EventType: "task.updated", Payload: `{}`, Attempts: 1}
+
+	code, body, err := sendWebhook(hook, delivery)
+	if err != nil || code != http.StatusInternalServerError || body != "boom\n" {
+		t.Fatalf("sendWebhook() = %d, %q, %v", code, body, err)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{}
+	recordDeliveryResult(updates, delivery.Attempts+1, code, body, err, now)
+	if _, ok := updates["status"]; ok {
+		t.Errorf("未达到最多尝试次数时不应结束投递: %v", updates)
+	}
+	if got := updates["next_attempt_at"]; got != now.Add(time.Minute) {
+		t.Errorf("next_attempt_at = %v, want %v", got, now.Add(time.Minute))
+	}
+
+	updates = map[string]interface{}{}
+	recordDeliveryResult(updates, maxWebhookAttempts, code, body, err, now)
+	if updates["status"] != "failed" {
+		t.Errorf("最后一次尝试失败后 status = %v, want failed", updates["status"])
+	}
+
+	updates = map[string]interface{}{}
+	recordDeliveryResult(updates, 1, 0, "", errors.New("connection refused"), now)
+	if updates["error"] != "connection refused" || updates["next_attempt_at"] != now.Add(30*time.Second) {
+		t.Errorf("连接失败的记录 = %v", updates)
+	}
+}
@@ -64,6 +64,7 @@ CREATE TABLE IF NOT EXISTS tasks (
     end_date DATE NOT NULL,
     status VARCHAR(20) DEFAULT 'pending' CHECK (status IN ('pending', 'in_progress', 'completed')),
     priority VARCHAR(20) DEFAULT 'medium' CHECK (priority IN ('low', 'medium', 'high', 'urgent')),
+    "order" INTEGER DEFAULT 0,
     progress DECIMAL(5,2) DEFAULT 0.00 CHECK (progress >= 0 AND progress <= 100),
     assigned_to INTEGER REFERENCES team_members(id),
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -88,6 +89,7 @@ CREATE INDEX IF NOT EXISTS idx_team_members_project_id ON team_members(project_i
 CREATE INDEX IF NOT EXISTS idx_team_members_role ON team_members(role);
 CREATE INDEX IF NOT EXISTS idx_stages_project_id ON stages(project_id);
 CREATE INDEX IF NOT EXISTS idx_stages_order ON stages("order");
+CREATE INDEX IF NOT EXISTS idx_tasks_stage_order ON tasks(stage_id, "order");
 CREATE INDEX IF NOT EXISTS idx_tasks_stage_id ON tasks(stage_id);
 CREATE INDEX IF NOT EXISTS idx_tasks_assigned_to ON tasks(assigned_to);
 CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
@@ -1,6 +1,6 @@
 import { defineStore } from 'pinia'
 import { ref, computed } from 'vue'
-import api from '../utils/api'
+import api, { ifMatch } from '../utils/api'
 
 export const useProjectStore = defineStore('project', () => {
   const projects = ref([])
@@ -88,11 +88,20 @@ export const useProjectStore = defineStore('project', () => {
     }
   }
   
+  // 获取已加载项目的版本号
+  const projectVersion = (id) => {
+    if (currentProject.value && currentProject.value.id === id) {
+      return currentProject.value.version
+    }
+    return projects.value.find(p => p.id === id)?.version
+  }
+  
   // 更新项目
   const updateProject = async (id, projectData) => {
     loading.value = true
     try {
-      const response = await api.put(`/projects/${id}`, projectData)
+      const version = projectData.version ?? projectVersion(id)
+      const response = await api.put(`/projects/${id}`, projectData, ifMatch(version))
       const index = projects.value.findIndex(p => p.id === id)
       if (index !== -1) {
         projects.value[index] = response.data
@@ -113,7 +122,7 @@ export const useProjectStore = defineStore('project', () => {
   const deleteProject = async (id) => {
     loading.value = true
     try {
-      await api.delete(`/projects/${id}`)
+      await api.delete(`/projects/${id}`, ifMatch(projectVersion(id)))
       projects.value = projects.value.filter(p => p.id !== id)
       if (currentProject.value && currentProject.value.id === id) {
         currentProject.value = null
@@ -58,4 +58,9 @@ api.interceptors.response.use(
   }
 )
 
+// 乐观锁：更新和删除时携带读取时的版本号
+export const ifMatch = (version) => ({
+  headers: { 'If-Match': `"${version}"` }
+})
+
 export default api